@@ -0,0 +1,109 @@
+package broadcast
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/broadcast"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/rs/zerolog"
+)
+
+func Init() {
+	log = app.GetLogger("broadcast")
+
+	var cfg struct {
+		Mod map[string]string `yaml:"broadcast"`
+	}
+	app.LoadConfig(&cfg)
+
+	for name, sinkURL := range cfg.Mod {
+		mgr := broadcast.NewManager(sourceFunc(name))
+
+		if err := mgr.Start(sinkURL); err != nil {
+			log.Error().Err(err).Str("stream", name).Msg("[broadcast] start")
+			continue
+		}
+
+		managersMu.Lock()
+		managers[name] = mgr
+		managersMu.Unlock()
+	}
+
+	api.HandleFunc("api/broadcast", apiHandler)
+}
+
+var log zerolog.Logger
+
+var (
+	managersMu sync.Mutex
+	managers   = map[string]*broadcast.Manager{}
+)
+
+// sourceFunc re-resolves the stream by name on every reconnect, so a
+// stream-source restart is picked up without recreating the Manager.
+func sourceFunc(name string) broadcast.Source {
+	return func() (core.Producer, error) {
+		stream := streams.Get(name)
+		if stream == nil {
+			return nil, fmt.Errorf("broadcast: unknown stream: %s", name)
+		}
+		return stream, nil
+	}
+}
+
+// apiHandler implements GET (list), POST (start/update) and DELETE (stop)
+// for api/broadcast?src=<stream>&dst=<sink url>.
+func apiHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		managersMu.Lock()
+		info := make(map[string]string, len(managers))
+		for name, mgr := range managers {
+			info[name] = mgr.URL()
+		}
+		managersMu.Unlock()
+		_ = json.NewEncoder(w).Encode(info)
+
+	case http.MethodPost:
+		src := r.URL.Query().Get("src")
+		dst := r.URL.Query().Get("dst")
+		if src == "" || dst == "" {
+			http.Error(w, "src and dst are required", http.StatusBadRequest)
+			return
+		}
+
+		managersMu.Lock()
+		mgr, ok := managers[src]
+		if !ok {
+			mgr = broadcast.NewManager(sourceFunc(src))
+			managers[src] = mgr
+		}
+		managersMu.Unlock()
+
+		if err := mgr.Start(dst); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+	case http.MethodDelete:
+		src := r.URL.Query().Get("src")
+
+		managersMu.Lock()
+		mgr, ok := managers[src]
+		delete(managers, src)
+		managersMu.Unlock()
+
+		if ok {
+			mgr.Stop()
+		}
+
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
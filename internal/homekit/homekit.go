@@ -1,6 +1,9 @@
 package homekit
 
 import (
+	"encoding/json"
+	"net/http"
+
 	"github.com/AlexxIT/go2rtc/internal/api"
 	"github.com/AlexxIT/go2rtc/internal/app"
 	"github.com/AlexxIT/go2rtc/internal/srtp"
@@ -16,10 +19,55 @@ func Init() {
 	streams.HandleFunc("homekit", streamHandler)
 
 	api.HandleFunc("api/homekit", apiHandler)
+	api.HandleFunc("api/homekit/pair", pairHandler)
+
+	var cfg struct {
+		Mod struct {
+			Publish map[string]*homekit.PublishConfig `yaml:"publish"`
+		} `yaml:"homekit"`
+	}
+	app.LoadConfig(&cfg)
+
+	for name, pubCfg := range cfg.Mod.Publish {
+		stream := streams.Get(name)
+		if stream == nil {
+			log.Warn().Str("stream", name).Msg("[homekit] unknown stream")
+			continue
+		}
+
+		srv, err := homekit.NewServer(name, stream, srtp.Server, pubCfg)
+		if err != nil {
+			log.Error().Err(err).Str("stream", name).Msg("[homekit] publish")
+			continue
+		}
+
+		if err = srv.Start(); err != nil {
+			log.Error().Err(err).Str("stream", name).Msg("[homekit] start")
+			continue
+		}
+
+		servers[name] = srv
+	}
 }
 
 var log zerolog.Logger
 
+var servers = map[string]*homekit.Server{}
+
 func streamHandler(url string) (core.Producer, error) {
 	return homekit.Dial(url, srtp.Server)
 }
+
+// pairHandler returns the setup PIN for a published stream, so the UI can
+// render the QR code iOS Home scans to add the accessory.
+func pairHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("src")
+
+	srv := servers[name]
+	if srv == nil {
+		http.Error(w, "unknown stream", http.StatusNotFound)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(srv.PairingInfo())
+}
@@ -2,6 +2,7 @@ package ascii
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
 	"image/jpeg"
 	"io"
@@ -9,7 +10,20 @@ import (
 	"unicode/utf8"
 )
 
+// NewWriter builds a terminal writer from MJPEG frames. foreground selects
+// either an xterm color mode ("8", "256", "rgb") for the default
+// character-cell renderer, or a true-image mode ("sixel", "kitty") that
+// ignores background/text and emits real pixels instead.
 func NewWriter(w io.Writer, foreground, background, text string) io.Writer {
+	switch foreground {
+	case "sixel":
+		_, _ = w.Write([]byte(csiClear))
+		return &sixelWriter{wr: w}
+	case "kitty":
+		_, _ = w.Write([]byte(csiClear))
+		return &kittyWriter{wr: w}
+	}
+
 	// once clear screen
 	_, _ = w.Write([]byte(csiClear))
 
@@ -154,3 +168,187 @@ func xterm256color(r, g, b uint8, n int) (index uint8) {
 	}
 	return
 }
+
+// sixelWriter renders MJPEG frames as DEC Sixel graphics, quantizing to the
+// same 256-color xterm palette used by the character-cell renderer.
+type sixelWriter struct {
+	wr io.Writer
+}
+
+// https://en.wikipedia.org/wiki/Sixel
+func (s *sixelWriter) Write(p []byte) (n int, err error) {
+	img, err := jpeg.Decode(bytes.NewReader(p))
+	if err != nil {
+		return 0, err
+	}
+
+	w := img.Bounds().Dx()
+	h := img.Bounds().Dy()
+
+	idx := make([]uint8, w*h)
+	used := make(map[uint8]bool)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			i := xterm256color(uint8(r>>8), uint8(g>>8), uint8(b>>8), 255)
+			idx[y*w+x] = i
+			used[i] = true
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(csiHome) // re-home the cursor so each frame overwrites the last
+	buf.WriteString("\033Pq")
+	buf.WriteString(fmt.Sprintf("\"1;1;%d;%d", w, h))
+
+	for i := range used {
+		buf.WriteString(fmt.Sprintf(
+			"#%d;2;%d;%d;%d", i, sixelScale(x256r[i]), sixelScale(x256g[i]), sixelScale(x256b[i]),
+		))
+	}
+
+	for y0 := 0; y0 < h; y0 += 6 {
+		rowColors := make(map[uint8]bool)
+		for x := 0; x < w; x++ {
+			for dy := 0; dy < 6 && y0+dy < h; dy++ {
+				rowColors[idx[(y0+dy)*w+x]] = true
+			}
+		}
+
+		first := true
+		for color := range rowColors {
+			if !first {
+				buf.WriteByte('$') // return to start of band, overlay next color
+			}
+			first = false
+
+			buf.WriteString(fmt.Sprintf("#%d", color))
+			writeSixelRun(&buf, idx, w, h, y0, color)
+		}
+
+		buf.WriteByte('-') // next band
+	}
+
+	buf.WriteString("\033\\")
+
+	if n, err = s.wr.Write(buf.Bytes()); err == nil {
+		s.wr.(http.Flusher).Flush()
+	}
+
+	return
+}
+
+// writeSixelRun emits one color's sixel bytes for the 6-pixel-tall band
+// starting at y0, run-length-encoding repeated bytes as "!count<char>".
+func writeSixelRun(buf *bytes.Buffer, idx []uint8, w, h, y0 int, color uint8) {
+	var run byte
+	var runLen int
+
+	flush := func() {
+		if runLen == 0 {
+			return
+		}
+		if runLen > 3 {
+			buf.WriteString(fmt.Sprintf("!%d%c", runLen, run))
+		} else {
+			for i := 0; i < runLen; i++ {
+				buf.WriteByte(run)
+			}
+		}
+		runLen = 0
+	}
+
+	for x := 0; x < w; x++ {
+		var bits byte
+		for dy := 0; dy < 6 && y0+dy < h; dy++ {
+			if idx[(y0+dy)*w+x] == color {
+				bits |= 1 << dy
+			}
+		}
+
+		c := byte(0x3F + bits)
+		if runLen > 0 && c == run {
+			runLen++
+			continue
+		}
+
+		flush()
+		run, runLen = c, 1
+	}
+
+	flush()
+}
+
+// sixelScale converts an 8-bit color component to sixel's 0-100 scale.
+func sixelScale(v uint8) int {
+	return int(v) * 100 / 255
+}
+
+// kittyWriter renders MJPEG frames via the Kitty terminal graphics protocol.
+// https://sw.kovidgoyal.net/kitty/graphics-protocol/
+type kittyWriter struct {
+	wr io.Writer
+}
+
+const kittyChunkSize = 4096
+
+// kittyImageID is reused for every frame: transmitting with the same i=
+// replaces the terminal's stored image data instead of accumulating a new
+// one per frame, which would otherwise grow the graphics cache without bound.
+const kittyImageID = 1
+
+func (k *kittyWriter) Write(p []byte) (n int, err error) {
+	img, err := jpeg.Decode(bytes.NewReader(p))
+	if err != nil {
+		return 0, err
+	}
+
+	w := img.Bounds().Dx()
+	h := img.Bounds().Dy()
+
+	rgba := make([]byte, 0, w*h*4)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			rgba = append(rgba, byte(r>>8), byte(g>>8), byte(b>>8), byte(a>>8))
+		}
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(rgba)
+
+	if _, err = k.wr.Write([]byte(csiHome)); err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < len(encoded); i += kittyChunkSize {
+		end := i + kittyChunkSize
+		last := end >= len(encoded)
+		if last {
+			end = len(encoded)
+		}
+
+		m := 1
+		if last {
+			m = 0
+		}
+
+		// Only the first chunk carries the image control keys; continuation
+		// chunks are keyed by i= and m= alone, per the Kitty graphics protocol.
+		var chunk string
+		if i == 0 {
+			chunk = fmt.Sprintf("\033_Gf=32,s=%d,v=%d,i=%d,a=T,m=%d;%s\033\\", w, h, kittyImageID, m, encoded[i:end])
+		} else {
+			chunk = fmt.Sprintf("\033_Gi=%d,m=%d;%s\033\\", kittyImageID, m, encoded[i:end])
+		}
+
+		if _, err = k.wr.Write([]byte(chunk)); err != nil {
+			return 0, err
+		}
+	}
+
+	n = len(p)
+	k.wr.(http.Flusher).Flush()
+
+	return
+}
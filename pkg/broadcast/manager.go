@@ -0,0 +1,129 @@
+// Package broadcast continuously republishes an existing core.Producer's
+// tracks to an external sink (RTMP, SRT or WHIP), independent of whatever
+// go2rtc consumers are already attached to the same stream.
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/flv"
+	"github.com/AlexxIT/go2rtc/pkg/mpegts"
+	"github.com/AlexxIT/go2rtc/pkg/whip"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// Source resolves the producer to broadcast from. It is called again on
+// every (re)connect, so a stream that restarts between attempts is picked
+// up transparently without tearing down the Manager.
+type Source func() (core.Producer, error)
+
+// Manager owns a single sink URL and the goroutine that keeps it fed.
+type Manager struct {
+	src Source
+
+	mu     sync.Mutex
+	url    string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewManager(src Source) *Manager {
+	return &Manager{src: src}
+}
+
+func (m *Manager) URL() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.url
+}
+
+// Start (re)points the manager at a new sink URL, stopping any previous
+// publish loop first.
+func (m *Manager) Start(rawURL string) error {
+	if _, err := url.Parse(rawURL); err != nil {
+		return err
+	}
+
+	m.Stop()
+
+	m.mu.Lock()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.url = rawURL
+	m.cancel = cancel
+	done := make(chan struct{})
+	m.done = done
+	m.mu.Unlock()
+
+	go m.run(ctx, rawURL, done)
+
+	return nil
+}
+
+// Stop tears down the publish loop without touching the upstream producer.
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	cancel := m.cancel
+	done := m.done
+	m.cancel = nil
+	m.done = nil
+	m.url = ""
+	m.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+func (m *Manager) run(ctx context.Context, rawURL string, done chan struct{}) {
+	defer close(done)
+
+	backoff := minBackoff
+
+	for ctx.Err() == nil {
+		if err := m.publishOnce(ctx, rawURL); err == nil {
+			backoff = minBackoff
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// publishOnce re-resolves the source, dials the sink once and blocks until
+// either side disconnects or ctx is cancelled.
+func (m *Manager) publishOnce(ctx context.Context, rawURL string) error {
+	producer, err := m.src()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case strings.HasPrefix(rawURL, "rtmp://"), strings.HasPrefix(rawURL, "rtmps://"):
+		return flv.Publish(ctx, rawURL, producer)
+	case strings.HasPrefix(rawURL, "srt://"):
+		return mpegts.Publish(ctx, rawURL, producer)
+	case strings.HasPrefix(rawURL, "whip+https://"), strings.HasPrefix(rawURL, "whip+http://"):
+		return whip.Publish(ctx, rawURL, producer)
+	default:
+		return errors.New("broadcast: unsupported sink URL: " + rawURL)
+	}
+}
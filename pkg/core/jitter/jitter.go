@@ -0,0 +1,144 @@
+// Package jitter implements a small, SSRC-keyed jitter buffer for
+// reordering RTP packets arriving out of order, which is common over Wi-Fi
+// links such as a HomeKit camera's SRTP session.
+package jitter
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/pion/rtp"
+)
+
+// Buffer reorders RTP packets for a single SSRC. Packets are released in
+// sequence order once either the next expected sequence number arrives,
+// the buffer grows past Depth, or the oldest packet has waited past Delay.
+type Buffer struct {
+	Delay time.Duration
+	Depth int
+
+	mu       sync.Mutex
+	queue    seqHeap
+	expected uint16
+	started  bool
+}
+
+// NewBuffer creates a buffer that flushes packets to out in sequence order.
+func NewBuffer(delay time.Duration, depth int) *Buffer {
+	return &Buffer{Delay: delay, Depth: depth}
+}
+
+// startupWindow is how many packets Push holds before trusting the lowest
+// sequence number seen as the start of the stream. Without it, the very
+// first packet would lock in b.expected on arrival, so a flow whose first
+// two packets arrive reordered (e.g. seq 6 before seq 5) would see seq 5
+// dropped as "already released" instead of reordered ahead of 6.
+const startupWindow = 2
+
+// Push inserts a packet and returns any packets now ready for output, in
+// order. Duplicate sequence numbers (retransmits) are dropped.
+func (b *Buffer) Push(packet *rtp.Packet) []*rtp.Packet {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	seq := packet.SequenceNumber
+
+	if b.started && seqLess(seq, b.expected) {
+		return nil // duplicate or already-released packet
+	}
+
+	for _, item := range b.queue {
+		if item.seq == seq {
+			return nil // duplicate still in the buffer
+		}
+	}
+
+	heap.Push(&b.queue, &seqItem{packet: packet, seq: seq, arrived: now})
+
+	var out []*rtp.Packet
+
+	for len(b.queue) > 0 {
+		next := b.queue[0]
+
+		if !b.started {
+			if len(b.queue) < startupWindow && len(b.queue) <= b.Depth && now.Sub(next.arrived) <= b.Delay {
+				break // still within the startup reordering window
+			}
+			b.expected = next.seq
+			b.started = true
+		}
+
+		ready := next.seq == b.expected ||
+			len(b.queue) > b.Depth ||
+			now.Sub(next.arrived) > b.Delay
+
+		if !ready {
+			break
+		}
+
+		heap.Pop(&b.queue)
+		out = append(out, next.packet)
+		b.expected = next.seq + 1 // advance past any gap, losses are not retransmitted
+	}
+
+	return out
+}
+
+// Demux fans a multi-SSRC RTP stream out into per-SSRC Buffers and emits
+// reordered packets to a single handler, preserving per-track ordering.
+type Demux struct {
+	Delay time.Duration
+	Depth int
+	Out   core.HandlerFunc
+
+	mu      sync.Mutex
+	buffers map[uint32]*Buffer
+}
+
+// NewDemux wires a jitter buffer as an OnReadRTP-style handler.
+func NewDemux(delay time.Duration, depth int, out core.HandlerFunc) *Demux {
+	return &Demux{Delay: delay, Depth: depth, Out: out, buffers: map[uint32]*Buffer{}}
+}
+
+func (d *Demux) WriteRTP(packet *rtp.Packet) {
+	d.mu.Lock()
+	buf, ok := d.buffers[packet.SSRC]
+	if !ok {
+		buf = NewBuffer(d.Delay, d.Depth)
+		d.buffers[packet.SSRC] = buf
+	}
+	d.mu.Unlock()
+
+	for _, out := range buf.Push(packet) {
+		d.Out(out)
+	}
+}
+
+// seqLess reports whether a precedes b, accounting for 16-bit wraparound.
+func seqLess(a, b uint16) bool {
+	return int16(a-b) < 0
+}
+
+type seqItem struct {
+	packet  *rtp.Packet
+	seq     uint16
+	arrived time.Time
+}
+
+type seqHeap []*seqItem
+
+func (h seqHeap) Len() int            { return len(h) }
+func (h seqHeap) Less(i, j int) bool  { return seqLess(h[i].seq, h[j].seq) }
+func (h seqHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *seqHeap) Push(x interface{}) { *h = append(*h, x.(*seqItem)) }
+
+func (h *seqHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,149 @@
+package jitter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+func pkt(seq uint16) *rtp.Packet {
+	return &rtp.Packet{Header: rtp.Header{SequenceNumber: seq}}
+}
+
+func seqs(packets []*rtp.Packet) []uint16 {
+	out := make([]uint16, len(packets))
+	for i, p := range packets {
+		out[i] = p.SequenceNumber
+	}
+	return out
+}
+
+func assertSeqs(t *testing.T, got []*rtp.Packet, want ...uint16) {
+	t.Helper()
+	gotSeqs := seqs(got)
+	if len(gotSeqs) != len(want) {
+		t.Fatalf("got %v, want %v", gotSeqs, want)
+	}
+	for i := range want {
+		if gotSeqs[i] != want[i] {
+			t.Fatalf("got %v, want %v", gotSeqs, want)
+		}
+	}
+}
+
+// The first packet of a stream is held for a short startup window rather
+// than releasing immediately, so a lower sequence number arriving right
+// after it can still overtake it (see TestBufferReordersFirstTwoPackets).
+func TestBufferInOrderPassthrough(t *testing.T) {
+	b := NewBuffer(time.Hour, 50)
+
+	assertSeqs(t, b.Push(pkt(1)))       // held for the startup window
+	assertSeqs(t, b.Push(pkt(2)), 1, 2) // startup resolves, both release in order
+	assertSeqs(t, b.Push(pkt(3)), 3)
+}
+
+func TestBufferReordersOutOfOrderPackets(t *testing.T) {
+	b := NewBuffer(time.Hour, 50)
+
+	assertSeqs(t, b.Push(pkt(1)))    // held for the startup window
+	assertSeqs(t, b.Push(pkt(3)), 1) // startup resolves to seq 1; 3 is a gap, withheld
+	assertSeqs(t, b.Push(pkt(2)), 2, 3)
+}
+
+// TestBufferReordersFirstTwoPackets guards the startup case the jitter
+// buffer exists for: if the very first two packets of a flow arrive
+// reordered, the lower sequence number must still come out first instead of
+// being dropped as "already released".
+func TestBufferReordersFirstTwoPackets(t *testing.T) {
+	b := NewBuffer(time.Hour, 50)
+
+	assertSeqs(t, b.Push(pkt(6))) // held for the startup window
+	assertSeqs(t, b.Push(pkt(5)), 5, 6)
+}
+
+func TestBufferDropsDuplicates(t *testing.T) {
+	b := NewBuffer(time.Hour, 50)
+
+	assertSeqs(t, b.Push(pkt(1)))    // held for the startup window
+	assertSeqs(t, b.Push(pkt(1)))    // duplicate of the held packet, dropped
+	assertSeqs(t, b.Push(pkt(3)), 1) // startup resolves to seq 1; 3 is a gap, withheld
+	assertSeqs(t, b.Push(pkt(3)))    // still queued, dropped as a duplicate
+	assertSeqs(t, b.Push(pkt(2)), 2, 3)
+}
+
+func TestBufferToleratesGaps(t *testing.T) {
+	b := NewBuffer(time.Hour, 50)
+
+	assertSeqs(t, b.Push(pkt(1)))       // held for the startup window
+	assertSeqs(t, b.Push(pkt(2)), 1, 2) // startup resolves
+	// seq 3 is lost; seq 4 arrives and waits for it.
+	assertSeqs(t, b.Push(pkt(4)))
+	// depth is large enough that seq 4 stays queued behind the missing seq 3
+	// until enough later packets force it out.
+	for seq := uint16(5); seq < 5+50; seq++ {
+		b.Push(pkt(seq))
+	}
+	out := b.Push(pkt(5 + 50))
+	if len(out) == 0 {
+		t.Fatal("expected depth-based flush to have released buffered packets by now")
+	}
+}
+
+// Once a forced flush releases the head of the queue, any packets that are
+// now contiguous release in the same call.
+func TestBufferForceFlushesOnDepth(t *testing.T) {
+	b := NewBuffer(time.Hour, 2)
+
+	assertSeqs(t, b.Push(pkt(1)))    // held for the startup window
+	assertSeqs(t, b.Push(pkt(5)), 1) // startup resolves to seq 1; 5 is a gap, queue len 1
+	assertSeqs(t, b.Push(pkt(6)))    // queue len 2, not yet > depth
+	out := b.Push(pkt(7))            // queue len 3 > depth 2: force-releases 5, then 6 and 7 follow contiguously
+	assertSeqs(t, out, 5, 6, 7)
+}
+
+func TestBufferForceFlushesOnDelay(t *testing.T) {
+	b := NewBuffer(10*time.Millisecond, 50)
+
+	assertSeqs(t, b.Push(pkt(1)))    // held for the startup window
+	assertSeqs(t, b.Push(pkt(3)), 1) // startup resolves to seq 1; 3 waits for seq 2
+
+	time.Sleep(20 * time.Millisecond)
+
+	out := b.Push(pkt(4))
+	assertSeqs(t, out, 3, 4)
+}
+
+func TestBufferHandlesSequenceWraparound(t *testing.T) {
+	b := NewBuffer(time.Hour, 50)
+
+	assertSeqs(t, b.Push(pkt(65534)))               // held for the startup window
+	assertSeqs(t, b.Push(pkt(65535)), 65534, 65535) // startup resolves, wraparound-aware
+	assertSeqs(t, b.Push(pkt(0)), 0)
+	assertSeqs(t, b.Push(pkt(1)), 1)
+}
+
+func TestDemuxKeepsPerSSRCOrder(t *testing.T) {
+	var outSSRC1, outSSRC2 []*rtp.Packet
+
+	demux := NewDemux(time.Hour, 50, func(p *rtp.Packet) {
+		if p.SSRC == 1 {
+			outSSRC1 = append(outSSRC1, p)
+		} else {
+			outSSRC2 = append(outSSRC2, p)
+		}
+	})
+
+	p := func(ssrc uint32, seq uint16) *rtp.Packet {
+		return &rtp.Packet{Header: rtp.Header{SSRC: ssrc, SequenceNumber: seq}}
+	}
+
+	demux.WriteRTP(p(1, 1))
+	demux.WriteRTP(p(2, 1))
+	demux.WriteRTP(p(1, 3)) // ssrc 1 out of order, waits
+	demux.WriteRTP(p(2, 2))
+	demux.WriteRTP(p(1, 2)) // fills the gap for ssrc 1
+
+	assertSeqs(t, outSSRC1, 1, 2, 3)
+	assertSeqs(t, outSSRC2, 1, 2)
+}
@@ -0,0 +1,71 @@
+package flv
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// Minimal AMF0 encoder: just enough value types to build the connect /
+// createStream / publish invoke messages RTMP publishing needs.
+
+type amf0String string
+type amf0Number float64
+type amf0Null struct{}
+type amf0Object map[string]interface{}
+
+// amf0Encode concatenates the AMF0 encoding of each value, in order, as a
+// single command-message payload.
+func amf0Encode(values ...interface{}) []byte {
+	var buf []byte
+	for _, v := range values {
+		buf = amf0AppendValue(buf, v)
+	}
+	return buf
+}
+
+func amf0AppendValue(buf []byte, v interface{}) []byte {
+	switch val := v.(type) {
+	case amf0String:
+		return amf0AppendString(buf, string(val))
+	case amf0Number:
+		buf = append(buf, 0x00)
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], math.Float64bits(float64(val)))
+		return append(buf, b[:]...)
+	case amf0Null:
+		return append(buf, 0x05)
+	case amf0Object:
+		buf = append(buf, 0x03) // object marker
+
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys) // deterministic encoding
+
+		for _, k := range keys {
+			buf = amf0AppendPropertyName(buf, k)
+			buf = amf0AppendValue(buf, val[k])
+		}
+		return append(amf0AppendPropertyName(buf, ""), 0x09) // empty key + object-end marker
+	default:
+		return buf
+	}
+}
+
+func amf0AppendString(buf []byte, s string) []byte {
+	buf = append(buf, 0x02) // string marker
+	buf = amf0AppendPropertyName(buf, s)
+	return buf
+}
+
+// amf0AppendPropertyName writes a UTF-8 string without its own type marker:
+// used both for plain string values (after the 0x02 marker) and for object
+// property names, which AMF0 never prefixes with a marker.
+func amf0AppendPropertyName(buf []byte, s string) []byte {
+	var n [2]byte
+	binary.BigEndian.PutUint16(n[:], uint16(len(s)))
+	buf = append(buf, n[:]...)
+	return append(buf, s...)
+}
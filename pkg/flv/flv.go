@@ -0,0 +1,412 @@
+// Package flv muxes a go2rtc producer's H264/AAC tracks into FLV tags and
+// publishes them over RTMP, for use by pkg/broadcast's RTMP/RTMPS sinks.
+//
+// RTP payloads are written into tags one-to-one: each RTP packet becomes one
+// NALU (video) or one AAC frame (audio). Fragmented H264 (FU-A) and RFC 3640
+// AAC-hbr framing are not reassembled here, since this tree has no pkg/h264
+// or pkg/aac depacketizer to reuse; producers that already hand out one NALU
+// or one AAC frame per RTP packet (as go2rtc's own RTSP/RTMP sources do) work
+// as-is, fragmented sources will not.
+//
+// Before any frame tag, attachTracks sends the AVCDecoderConfigurationRecord
+// (video) and/or AudioSpecificConfig (audio) sequence header a real decoder
+// needs to initialize, extracted from the codec's SDP fmtp line. A codec
+// whose fmtp carries neither sprop-parameter-sets nor config (e.g. one that
+// sends its parameter sets in-band instead) gets no sequence header, and
+// most decoders still won't be able to start.
+package flv
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/pion/rtp"
+)
+
+const (
+	tagTypeAudio = 8
+	tagTypeVideo = 9
+
+	codecIDH264 = 7
+
+	frameTypeKey   = 1
+	frameTypeInter = 2
+
+	avcPacketTypeSequenceHeader = 0
+	avcPacketTypeNALU           = 1
+
+	soundFormatAAC = 10
+
+	aacPacketTypeSequenceHeader = 0
+	aacPacketTypeRaw            = 1
+)
+
+// Publish dials rawURL (rtmp:// or rtmps://), completes the RTMP handshake
+// and publish sequence, then streams producer's video/audio tracks as FLV
+// tags until ctx is cancelled or the connection fails.
+func Publish(ctx context.Context, rawURL string, producer core.Producer) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dial(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err = handshake(conn); err != nil {
+		return err
+	}
+
+	w := newChunkWriter(conn)
+
+	streamKey := strings.TrimPrefix(u.Path, "/")
+	if err = publishCommands(w, u.Host, streamKey); err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 2)
+	attached := attachTracks(producer, w, errCh)
+	if !attached {
+		return errors.New("flv: producer has no video or audio media")
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err = <-errCh:
+		return err
+	}
+}
+
+func dial(ctx context.Context, u *url.URL) (net.Conn, error) {
+	addr := u.Host
+	if !strings.Contains(addr, ":") {
+		addr += ":1935"
+	}
+
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+// handshake performs RTMP's uncompressed (simple) handshake: C0/C1 out,
+// S0/S1/S2 in, C2 (S1 echoed back) out.
+func handshake(conn net.Conn) error {
+	c1 := make([]byte, 1536)
+	binary.BigEndian.PutUint32(c1[0:4], uint32(time.Now().Unix()))
+
+	if _, err := conn.Write(append([]byte{3}, c1...)); err != nil {
+		return err
+	}
+
+	s0s1s2 := make([]byte, 1+1536+1536)
+	if _, err := readFull(conn, s0s1s2); err != nil {
+		return err
+	}
+	if s0s1s2[0] != 3 {
+		return errors.New("flv: unsupported RTMP version")
+	}
+	s1 := s0s1s2[1 : 1+1536]
+
+	_, err := conn.Write(s1)
+	return err
+}
+
+func readFull(conn net.Conn, buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := conn.Read(buf[n:])
+		if err != nil {
+			return n, err
+		}
+		n += m
+	}
+	return n, nil
+}
+
+// publishCommands sends the AMF0 connect/createStream/publish invoke
+// sequence. Replies are not parsed: go2rtc is acting purely as a one-way
+// publisher and has nothing useful to do with NetConnection/NetStream
+// status events.
+func publishCommands(w *chunkWriter, app, streamKey string) error {
+	connectCmd := amf0Encode(
+		amf0String("connect"),
+		amf0Number(1),
+		amf0Object{"app": amf0String(app), "flashVer": amf0String("go2rtc")},
+	)
+	if err := w.writeCommand(connectCmd); err != nil {
+		return err
+	}
+
+	createStreamCmd := amf0Encode(
+		amf0String("createStream"),
+		amf0Number(2),
+		amf0Null{},
+	)
+	if err := w.writeCommand(createStreamCmd); err != nil {
+		return err
+	}
+
+	publishCmd := amf0Encode(
+		amf0String("publish"),
+		amf0Number(3),
+		amf0Null{},
+		amf0String(streamKey),
+		amf0String("live"),
+	)
+	return w.writeCommand(publishCmd)
+}
+
+// attachTracks wires an audio and/or video consumer onto producer, each
+// writing FLV tags to w. It reports true if at least one track attached.
+func attachTracks(producer core.Producer, w *chunkWriter, errCh chan<- error) bool {
+	attached := false
+
+	for _, media := range producer.GetMedias() {
+		if len(media.Codecs) == 0 {
+			continue
+		}
+		codec := media.Codecs[0]
+
+		switch media.Kind {
+		case core.KindVideo:
+			if codec.Name != core.CodecH264 {
+				continue
+			}
+			receiver, err := producer.GetTrack(media, codec)
+			if err != nil {
+				continue
+			}
+			if sps, pps, ok := parseSpropParameterSets(codec.FmtpLine); ok {
+				if err := w.writeVideoSequenceHeader(sps, pps); err != nil {
+					select {
+					case errCh <- err:
+					default:
+					}
+				}
+			}
+			sender := core.NewSender(media, codec)
+			sender.Handler = func(packet *rtp.Packet) {
+				if err := w.writeVideoTag(packet); err != nil {
+					select {
+					case errCh <- err:
+					default:
+					}
+				}
+			}
+			sender.HandleRTP(receiver)
+			attached = true
+
+		case core.KindAudio:
+			if codec.Name != core.CodecAAC {
+				continue
+			}
+			receiver, err := producer.GetTrack(media, codec)
+			if err != nil {
+				continue
+			}
+			if config, ok := parseAACConfig(codec.FmtpLine); ok {
+				if err := w.writeAudioSequenceHeader(config); err != nil {
+					select {
+					case errCh <- err:
+					default:
+					}
+				}
+			}
+			sender := core.NewSender(media, codec)
+			sender.Handler = func(packet *rtp.Packet) {
+				if err := w.writeAudioTag(packet); err != nil {
+					select {
+					case errCh <- err:
+					default:
+					}
+				}
+			}
+			sender.HandleRTP(receiver)
+			attached = true
+		}
+	}
+
+	return attached
+}
+
+// chunkWriter sends RTMP messages as a single chunk-type-0 header per
+// message on chunk stream 3, and FLV tags as type 8/9 messages.
+type chunkWriter struct {
+	w        *bufio.Writer
+	start    time.Time
+	streamID uint32
+}
+
+func newChunkWriter(conn net.Conn) *chunkWriter {
+	return &chunkWriter{w: bufio.NewWriter(conn), start: time.Now()}
+}
+
+func (w *chunkWriter) timestamp() uint32 {
+	return uint32(time.Since(w.start).Milliseconds())
+}
+
+func (w *chunkWriter) writeCommand(payload []byte) error {
+	return w.writeMessage(20, 0, payload) // message type 20: AMF0 command
+}
+
+func (w *chunkWriter) writeVideoTag(packet *rtp.Packet) error {
+	frameType := byte(frameTypeInter)
+	if isKeyframeNALU(packet.Payload) {
+		frameType = frameTypeKey
+	}
+
+	payload := make([]byte, 9+len(packet.Payload))
+	payload[0] = frameType<<4 | codecIDH264
+	payload[1] = avcPacketTypeNALU
+	// composition time (3 bytes), always 0: no B-frame reordering here
+	binary.BigEndian.PutUint32(payload[5:9], uint32(len(packet.Payload)))
+	copy(payload[9:], packet.Payload)
+
+	return w.writeMessage(tagTypeVideo, w.timestamp(), payload)
+}
+
+// writeVideoSequenceHeader sends the AVCDecoderConfigurationRecord a decoder
+// needs before it can make sense of any AVCPacketType=1 (NALU) tag.
+func (w *chunkWriter) writeVideoSequenceHeader(sps, pps []byte) error {
+	record := avcDecoderConfigurationRecord(sps, pps)
+
+	payload := make([]byte, 5+len(record))
+	payload[0] = frameTypeKey<<4 | codecIDH264
+	payload[1] = avcPacketTypeSequenceHeader
+	// composition time (3 bytes), always 0 for a sequence header
+	copy(payload[5:], record)
+
+	return w.writeMessage(tagTypeVideo, w.timestamp(), payload)
+}
+
+// avcDecoderConfigurationRecord builds the ISO 14496-15 record FLV's
+// AVCPacketType=0 sequence header carries: version, profile/level (read
+// straight off the SPS), a fixed 4-byte NALU length size, then the SPS and
+// PPS themselves, each length-prefixed.
+func avcDecoderConfigurationRecord(sps, pps []byte) []byte {
+	record := []byte{
+		1,                      // configurationVersion
+		sps[1], sps[2], sps[3], // AVCProfileIndication, profile_compatibility, AVCLevelIndication
+		0xFF, // reserved (111111b) | lengthSizeMinusOne=3 (4-byte lengths)
+		0xE1, // reserved (111b) | numOfSequenceParameterSets=1
+		byte(len(sps) >> 8), byte(len(sps)),
+	}
+	record = append(record, sps...)
+	record = append(record, 1) // numOfPictureParameterSets
+	record = append(record, byte(len(pps)>>8), byte(len(pps)))
+	record = append(record, pps...)
+	return record
+}
+
+// writeAudioSequenceHeader sends the raw AudioSpecificConfig a decoder needs
+// before it can make sense of any AACPacketType=1 (raw) tag.
+func (w *chunkWriter) writeAudioSequenceHeader(config []byte) error {
+	payload := make([]byte, 2+len(config))
+	payload[0] = soundFormatAAC<<4 | 0x0F // 44kHz, 16-bit, stereo placeholder
+	payload[1] = aacPacketTypeSequenceHeader
+	copy(payload[2:], config)
+
+	return w.writeMessage(tagTypeAudio, w.timestamp(), payload)
+}
+
+// parseSpropParameterSets extracts the H264 SPS/PPS an AVCDecoderConfigurationRecord
+// needs from codec's SDP fmtp line (the "sprop-parameter-sets=<sps>,<pps>"
+// parameter RFC 6184 defines), reporting ok=false if fmtp carries none.
+func parseSpropParameterSets(fmtpLine string) (sps, pps []byte, ok bool) {
+	const key = "sprop-parameter-sets="
+	i := strings.Index(fmtpLine, key)
+	if i < 0 {
+		return nil, nil, false
+	}
+
+	value := fmtpLine[i+len(key):]
+	if j := strings.IndexByte(value, ';'); j >= 0 {
+		value = value[:j]
+	}
+
+	parts := strings.SplitN(value, ",", 2)
+	if len(parts) != 2 {
+		return nil, nil, false
+	}
+
+	sps, err1 := base64.StdEncoding.DecodeString(parts[0])
+	pps, err2 := base64.StdEncoding.DecodeString(parts[1])
+	if err1 != nil || err2 != nil || len(sps) < 4 || len(pps) == 0 {
+		return nil, nil, false
+	}
+
+	return sps, pps, true
+}
+
+// parseAACConfig extracts the raw AudioSpecificConfig bytes from codec's SDP
+// fmtp line (the "config=<hex>" parameter RFC 3640 defines), reporting
+// ok=false if fmtp carries none.
+func parseAACConfig(fmtpLine string) (config []byte, ok bool) {
+	const key = "config="
+	i := strings.Index(fmtpLine, key)
+	if i < 0 {
+		return nil, false
+	}
+
+	value := fmtpLine[i+len(key):]
+	if j := strings.IndexByte(value, ';'); j >= 0 {
+		value = value[:j]
+	}
+
+	config, err := hex.DecodeString(strings.TrimSpace(value))
+	if err != nil || len(config) == 0 {
+		return nil, false
+	}
+
+	return config, true
+}
+
+func (w *chunkWriter) writeAudioTag(packet *rtp.Packet) error {
+	payload := make([]byte, 2+len(packet.Payload))
+	payload[0] = soundFormatAAC<<4 | 0x0F // 44kHz, 16-bit, stereo placeholder
+	payload[1] = aacPacketTypeRaw
+	copy(payload[2:], packet.Payload)
+
+	return w.writeMessage(tagTypeAudio, w.timestamp(), payload)
+}
+
+func (w *chunkWriter) writeMessage(messageType byte, timestamp uint32, payload []byte) error {
+	header := make([]byte, 12)
+	header[0] = 3 // chunk stream id 3, chunk type 0 (fmt=0)
+	putUint24(header[1:4], timestamp)
+	putUint24(header[4:7], uint32(len(payload)))
+	header[7] = messageType
+	// message stream id stays 0: go2rtc never requested a non-default one
+
+	if _, err := w.w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(payload); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0] = byte(v >> 16)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v)
+}
+
+// isKeyframeNALU reports whether payload's NALU type (low 5 bits of the
+// first byte) is an IDR slice.
+func isKeyframeNALU(payload []byte) bool {
+	return len(payload) > 0 && payload[0]&0x1F == 5
+}
@@ -0,0 +1,138 @@
+package flv
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/pion/rtp"
+)
+
+func TestAMF0EncodeString(t *testing.T) {
+	got := amf0Encode(amf0String("connect"))
+	want := []byte{0x02, 0x00, 0x07, 'c', 'o', 'n', 'n', 'e', 'c', 't'}
+	if string(got) != string(want) {
+		t.Fatalf("got %x, want %x", got, want)
+	}
+}
+
+func TestAMF0EncodeNumber(t *testing.T) {
+	got := amf0Encode(amf0Number(1))
+	if got[0] != 0x00 || len(got) != 9 {
+		t.Fatalf("unexpected number encoding: %x", got)
+	}
+}
+
+func TestAMF0EncodeObjectIsSortedAndTerminated(t *testing.T) {
+	got := amf0Encode(amf0Object{"b": amf0Number(2), "a": amf0String("x")})
+
+	if got[0] != 0x03 {
+		t.Fatalf("expected object marker, got %x", got[0])
+	}
+	// object-end marker is the trailing empty-key + 0x09
+	if got[len(got)-3] != 0 || got[len(got)-2] != 0 || got[len(got)-1] != 0x09 {
+		t.Fatalf("missing object-end marker: %x", got[len(got)-3:])
+	}
+}
+
+func TestIsKeyframeNALU(t *testing.T) {
+	keyframe := &rtp.Packet{Payload: []byte{0x65, 1, 2, 3}}   // NALU type 5 (IDR)
+	interFrame := &rtp.Packet{Payload: []byte{0x61, 1, 2, 3}} // NALU type 1
+
+	if !isKeyframeNALU(keyframe.Payload) {
+		t.Fatal("expected IDR NALU to be detected as a keyframe")
+	}
+	if isKeyframeNALU(interFrame.Payload) {
+		t.Fatal("expected non-IDR NALU to not be detected as a keyframe")
+	}
+}
+
+func TestParseSpropParameterSets(t *testing.T) {
+	// base64("\x67\x42\xC0\x1E") / base64("\x68\xCE\x06\xE2")
+	const fmtp = "profile-level-id=42C01E;sprop-parameter-sets=Z0LAHg==,aM4G4g==;packetization-mode=1"
+
+	sps, pps, ok := parseSpropParameterSets(fmtp)
+	if !ok {
+		t.Fatal("expected sprop-parameter-sets to be found")
+	}
+	if string(sps) != "\x67\x42\xC0\x1E" {
+		t.Fatalf("unexpected sps: %x", sps)
+	}
+	if string(pps) != "\x68\xCE\x06\xE2" {
+		t.Fatalf("unexpected pps: %x", pps)
+	}
+
+	if _, _, ok := parseSpropParameterSets("packetization-mode=1"); ok {
+		t.Fatal("expected no sprop-parameter-sets to be found")
+	}
+}
+
+func TestAVCDecoderConfigurationRecord(t *testing.T) {
+	sps := []byte{0x67, 0x42, 0xC0, 0x1E}
+	pps := []byte{0x68, 0xCE, 0x06, 0xE2}
+
+	record := avcDecoderConfigurationRecord(sps, pps)
+
+	if record[0] != 1 {
+		t.Fatalf("expected configurationVersion 1, got %d", record[0])
+	}
+	if record[1] != sps[1] || record[2] != sps[2] || record[3] != sps[3] {
+		t.Fatalf("expected profile/compat/level copied from sps, got %x", record[1:4])
+	}
+	if record[4] != 0xFF {
+		t.Fatalf("expected lengthSizeMinusOne=3 byte 0xFF, got %x", record[4])
+	}
+	if record[5] != 0xE1 {
+		t.Fatalf("expected numOfSequenceParameterSets=1 byte 0xE1, got %x", record[5])
+	}
+
+	spsLen := int(record[6])<<8 | int(record[7])
+	if spsLen != len(sps) {
+		t.Fatalf("sps length prefix mismatch: got %d, want %d", spsLen, len(sps))
+	}
+	if string(record[8:8+spsLen]) != string(sps) {
+		t.Fatal("sps bytes not preserved")
+	}
+
+	rest := record[8+spsLen:]
+	if rest[0] != 1 {
+		t.Fatalf("expected numOfPictureParameterSets=1, got %d", rest[0])
+	}
+	ppsLen := int(rest[1])<<8 | int(rest[2])
+	if ppsLen != len(pps) || string(rest[3:3+ppsLen]) != string(pps) {
+		t.Fatal("pps bytes not preserved")
+	}
+}
+
+func TestParseAACConfig(t *testing.T) {
+	const fmtp = "profile-level-id=1;mode=AAC-hbr;config=1190;sizelength=13"
+
+	config, ok := parseAACConfig(fmtp)
+	if !ok {
+		t.Fatal("expected config to be found")
+	}
+	if string(config) != "\x11\x90" {
+		t.Fatalf("unexpected config: %x", config)
+	}
+
+	if _, ok := parseAACConfig("mode=AAC-hbr"); ok {
+		t.Fatal("expected no config to be found")
+	}
+}
+
+func TestNALULengthPrefixing(t *testing.T) {
+	payload := []byte{0x65, 0xAA, 0xBB}
+
+	tag := make([]byte, 9+len(payload))
+	tag[0] = frameTypeKey<<4 | codecIDH264
+	tag[1] = avcPacketTypeNALU
+	binary.BigEndian.PutUint32(tag[5:9], uint32(len(payload)))
+	copy(tag[9:], payload)
+
+	gotLen := binary.BigEndian.Uint32(tag[5:9])
+	if int(gotLen) != len(payload) {
+		t.Fatalf("length prefix mismatch: got %d, want %d", gotLen, len(payload))
+	}
+	if string(tag[9:]) != string(payload) {
+		t.Fatal("NALU bytes not preserved after the length prefix")
+	}
+}
@@ -0,0 +1,61 @@
+package accessory
+
+// Characteristic mirrors the JSON accessory-object model HAP uses for both
+// /accessories responses and PUT /characteristics writes.
+type Characteristic struct {
+	IID    uint64      `json:"iid"`
+	Type   string      `json:"type"`
+	Value  interface{} `json:"value,omitempty"`
+	Perms  []string    `json:"perms"`
+	Format string      `json:"format"`
+	Ev     bool        `json:"ev,omitempty"`
+}
+
+type Service struct {
+	IID             uint64           `json:"iid"`
+	Type            string           `json:"type"`
+	Characteristics []Characteristic `json:"characteristics"`
+}
+
+type Accessory struct {
+	AID      uint64    `json:"aid"`
+	Services []Service `json:"services"`
+}
+
+// HandleWrite applies a PUT /characteristics body to the accessory's
+// characteristics, returning the IIDs that changed value so the caller can
+// act on them (e.g. SelectedStreamConfiguration triggering SRTP bring-up).
+func (a *Accessory) HandleWrite(writes []Characteristic) (changed []uint64) {
+	for _, w := range writes {
+		for si := range a.Services {
+			svc := &a.Services[si]
+			for ci := range svc.Characteristics {
+				ch := &svc.Characteristics[ci]
+				if ch.IID != w.IID {
+					continue
+				}
+				if w.Value != nil {
+					ch.Value = w.Value
+					changed = append(changed, ch.IID)
+				}
+				if w.Ev {
+					ch.Ev = true
+				}
+			}
+		}
+	}
+	return
+}
+
+// Characteristic returns the characteristic with the given IID, or nil.
+func (a *Accessory) Characteristic(iid uint64) *Characteristic {
+	for si := range a.Services {
+		svc := &a.Services[si]
+		for ci := range svc.Characteristics {
+			if svc.Characteristics[ci].IID == iid {
+				return &svc.Characteristics[ci]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package accessory
+
+import (
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+func newSHA512() hash.Hash {
+	return sha512.New()
+}
+
+// TLV8 state machine types, as defined by the HAP Accessory Protocol spec.
+const (
+	TypeMethod     = 0x00
+	TypeIdentifier = 0x01
+	TypeSalt       = 0x02
+	TypePublicKey  = 0x03
+	TypeProof      = 0x04
+	TypeEncrypted  = 0x05
+	TypeState      = 0x06
+	TypeError      = 0x07
+	TypeSignature  = 0x0A
+)
+
+// Pairing is the one long-term pairing go2rtc keeps per published accessory.
+// iOS Home only ever completes pair-setup once; everything after that is
+// pair-verify against this record.
+type Pairing struct {
+	ControllerID        []byte
+	ControllerPublicKey ed25519.PublicKey
+}
+
+// Identity is the accessory's own long-term Ed25519 keypair, generated once
+// and reused for every pair-verify handshake.
+type Identity struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+}
+
+func NewIdentity(id string) (*Identity, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{ID: id, PrivateKey: priv, PublicKey: pub}, nil
+}
+
+// SetupSession drives the M1-M6 pair-setup exchange for a single PIN.
+type SetupSession struct {
+	identity *Identity
+	pin      string
+	srp      *ServerSession
+}
+
+func NewSetupSession(identity *Identity, pin string) *SetupSession {
+	return &SetupSession{identity: identity, pin: pin}
+}
+
+// StartM1 answers the controller's <M1,start request> with <M2,salt+B>.
+func (s *SetupSession) StartM1() (salt []byte, public *big.Int, err error) {
+	salt = make([]byte, 16)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, nil, err
+	}
+
+	verifier := Verifier(salt, srpSetupUsername, s.pin)
+	s.srp = NewServerSession(salt, verifier)
+
+	return salt, s.srp.Public(), nil
+}
+
+// VerifyM3 answers <M3,A+M1> with the server proof M2, completing SRP. The
+// controller's proof is recomputed independently and compared in constant
+// time before any proof is released; a mismatch aborts pair-setup.
+func (s *SetupSession) VerifyM3(A *big.Int, clientProof []byte) (serverProof []byte, err error) {
+	if s.srp == nil {
+		return nil, errors.New("hap: pair-setup not started")
+	}
+
+	if err = s.srp.ComputeKey(A); err != nil {
+		return nil, err
+	}
+
+	expected := s.srp.ClientProof(A)
+	if subtle.ConstantTimeCompare(clientProof, expected) != 1 {
+		return nil, errors.New("hap: pair-setup SRP proof mismatch")
+	}
+
+	return s.srp.ServerProof(A, clientProof), nil
+}
+
+// SessionKey exposes the SRP shared secret so the pair-setup TLV encryption
+// (M5/M6) can be derived via HKDF, matching the pair-verify key schedule.
+func (s *SetupSession) SessionKey() []byte {
+	return s.srp.SessionKey()
+}
+
+// VerifyM5 opens the controller's <M5,encrypted> sub-TLV, checks its
+// signature against the long-term Ed25519 key it just presented, and
+// returns both the Pairing to persist and the accessory's own <M6,encrypted>
+// sub-TLV to send back.
+func (s *SetupSession) VerifyM5(identity *Identity, encrypted []byte) (pairing *Pairing, reply []byte, err error) {
+	key, err := hkdfKey(s.srp.SessionKey(), "Pair-Setup-Encrypt-Salt", "Pair-Setup-Encrypt-Info")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sub, err := aead.Open(nil, pairSetupNonce("PS-Msg05"), encrypted, nil)
+	if err != nil {
+		return nil, nil, errors.New("hap: pair-setup M5 decryption failed")
+	}
+
+	fields := TLV8Decode(sub)
+	controllerID := fields[TypeIdentifier]
+	controllerPublic := ed25519.PublicKey(fields[TypePublicKey])
+	signature := fields[TypeSignature]
+
+	controllerX, err := hkdfKey(s.srp.SessionKey(), "Pair-Setup-Controller-Sign-Salt", "Pair-Setup-Controller-Sign-Info")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info := append(append(append([]byte{}, controllerX[:]...), controllerID...), controllerPublic...)
+	if !ed25519.Verify(controllerPublic, info, signature) {
+		return nil, nil, errors.New("hap: pair-setup M5 signature invalid")
+	}
+
+	accessoryX, err := hkdfKey(s.srp.SessionKey(), "Pair-Setup-Accessory-Sign-Salt", "Pair-Setup-Accessory-Sign-Info")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accessoryInfo := append(append(append([]byte{}, accessoryX[:]...), []byte(identity.ID)...), identity.PublicKey...)
+	accessorySignature := ed25519.Sign(identity.PrivateKey, accessoryInfo)
+
+	sub = TLV8Encode(
+		TLV8Item{Type: TypeIdentifier, Value: []byte(identity.ID)},
+		TLV8Item{Type: TypePublicKey, Value: identity.PublicKey},
+		TLV8Item{Type: TypeSignature, Value: accessorySignature},
+	)
+	reply = aead.Seal(nil, pairSetupNonce("PS-Msg06"), sub, nil)
+
+	pairing = &Pairing{
+		ControllerID:        append([]byte{}, controllerID...),
+		ControllerPublicKey: append(ed25519.PublicKey{}, controllerPublic...),
+	}
+
+	return pairing, reply, nil
+}
+
+// pairSetupNonce builds the fixed 12-byte nonce HAP uses for each pair-setup
+// TLV encryption step: 4 zero bytes followed by the step's literal label.
+func pairSetupNonce(label string) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce[4:], label)
+	return nonce
+}
+
+// hkdfKey derives a 32-byte key from secret via HKDF-SHA512, as used
+// throughout the pair-setup and pair-verify key schedules.
+func hkdfKey(secret []byte, salt, info string) ([32]byte, error) {
+	var key [32]byte
+	_, err := io.ReadFull(hkdf.New(newSHA512, secret, []byte(salt), []byte(info)), key[:])
+	return key, err
+}
+
+// VerifySession drives the per-connection M1-M4 pair-verify handshake that
+// establishes the control-channel ChaCha20-Poly1305 keys.
+type VerifySession struct {
+	identity *Identity
+	pairing  *Pairing
+
+	localPrivate, localPublic [32]byte
+	remotePublic              [32]byte
+	sharedSecret              [32]byte
+}
+
+func NewVerifySession(identity *Identity, pairing *Pairing) *VerifySession {
+	return &VerifySession{identity: identity, pairing: pairing}
+}
+
+// StartM1 generates the accessory's ephemeral Curve25519 keypair and signs
+// the transcript, answering <M1,controller public key> with <M2>.
+func (s *VerifySession) StartM1(controllerPublic [32]byte) (accessoryPublic [32]byte, signature []byte, err error) {
+	s.remotePublic = controllerPublic
+
+	if _, err = rand.Read(s.localPrivate[:]); err != nil {
+		return accessoryPublic, nil, err
+	}
+
+	pub, err := curve25519.X25519(s.localPrivate[:], curve25519.Basepoint)
+	if err != nil {
+		return accessoryPublic, nil, err
+	}
+	copy(s.localPublic[:], pub)
+	copy(accessoryPublic[:], pub)
+
+	shared, err := curve25519.X25519(s.localPrivate[:], controllerPublic[:])
+	if err != nil {
+		return accessoryPublic, nil, err
+	}
+	copy(s.sharedSecret[:], shared)
+
+	info := append(append([]byte{}, accessoryPublic[:]...), []byte(s.identity.ID)...)
+	info = append(info, controllerPublic[:]...)
+	signature = ed25519.Sign(s.identity.PrivateKey, info)
+
+	return accessoryPublic, signature, nil
+}
+
+// ControlKeys derives the read/write encryption keys for the HAP control
+// channel from the pair-verify shared secret (HKDF-SHA512, per spec).
+func (s *VerifySession) ControlKeys() (accessoryToController, controllerToAccessory [32]byte, err error) {
+	out := hkdf.New(newSHA512, s.sharedSecret[:], []byte("Control-Salt"), []byte("Control-Read-Encryption-Key"))
+	if _, err = out.Read(accessoryToController[:]); err != nil {
+		return
+	}
+
+	in := hkdf.New(newSHA512, s.sharedSecret[:], []byte("Control-Salt"), []byte("Control-Write-Encryption-Key"))
+	_, err = in.Read(controllerToAccessory[:])
+	return
+}
+
+// EncryptM2 seals the accessory's identity and transcript signature into the
+// sub-TLV carried by <M2,encrypted>.
+func (s *VerifySession) EncryptM2(signature []byte) ([]byte, error) {
+	key, err := hkdfKey(s.sharedSecret[:], "Pair-Verify-Encrypt-Salt", "Pair-Verify-Encrypt-Info")
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := TLV8Encode(
+		TLV8Item{Type: TypeIdentifier, Value: []byte(s.identity.ID)},
+		TLV8Item{Type: TypeSignature, Value: signature},
+	)
+
+	return aead.Seal(nil, pairSetupNonce("PV-Msg02"), sub, nil), nil
+}
+
+// VerifyM3 opens the controller's <M3,encrypted> sub-TLV and checks its
+// signature and identity against the stored Pairing, completing pair-verify.
+// A nil pairing (no prior pair-setup) or any mismatch aborts the handshake.
+func (s *VerifySession) VerifyM3(encrypted []byte) error {
+	if s.pairing == nil {
+		return errors.New("hap: no completed pairing for this accessory")
+	}
+
+	key, err := hkdfKey(s.sharedSecret[:], "Pair-Verify-Encrypt-Salt", "Pair-Verify-Encrypt-Info")
+	if err != nil {
+		return err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	sub, err := aead.Open(nil, pairSetupNonce("PV-Msg03"), encrypted, nil)
+	if err != nil {
+		return errors.New("hap: pair-verify M3 decryption failed")
+	}
+
+	fields := TLV8Decode(sub)
+	controllerID := fields[TypeIdentifier]
+	signature := fields[TypeSignature]
+
+	if subtle.ConstantTimeCompare(controllerID, s.pairing.ControllerID) != 1 {
+		return errors.New("hap: pair-verify controller id mismatch")
+	}
+
+	info := append(append(append([]byte{}, s.remotePublic[:]...), controllerID...), s.localPublic[:]...)
+	if !ed25519.Verify(s.pairing.ControllerPublicKey, info, signature) {
+		return errors.New("hap: pair-verify controller signature invalid")
+	}
+
+	return nil
+}
+
+func newAEAD(key [32]byte) (cipher.AEAD, error) {
+	return chacha20poly1305.New(key[:])
+}
@@ -0,0 +1,314 @@
+package accessory
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestTLV8EncodeDecode(t *testing.T) {
+	enc := TLV8Encode(
+		TLV8Item{Type: TypeState, Value: []byte{2}},
+		TLV8Item{Type: TypeIdentifier, Value: []byte("abc")},
+	)
+
+	dec := TLV8Decode(enc)
+
+	if len(dec[TypeState]) != 1 || dec[TypeState][0] != 2 {
+		t.Fatalf("state mismatch: %x", dec[TypeState])
+	}
+	if string(dec[TypeIdentifier]) != "abc" {
+		t.Fatalf("identifier mismatch: %q", dec[TypeIdentifier])
+	}
+}
+
+// controllerVerify reimplements the controller side of pair-verify
+// independently of VerifySession, exercising the real wire handshake.
+type controllerVerify struct {
+	private, public [32]byte
+	signKey         ed25519.PrivateKey
+	verifyKey       ed25519.PublicKey
+	id              []byte
+}
+
+func newControllerVerify(t *testing.T) *controllerVerify {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &controllerVerify{signKey: priv, verifyKey: pub, id: []byte("controller-1")}
+	if _, err := rand.Read(c.private[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	pubBytes, err := curve25519.X25519(c.private[:], curve25519.Basepoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(c.public[:], pubBytes)
+
+	return c
+}
+
+func (c *controllerVerify) m3Encrypted(t *testing.T, accessoryPublic [32]byte) []byte {
+	t.Helper()
+
+	shared, err := curve25519.X25519(c.private[:], accessoryPublic[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sharedArr [32]byte
+	copy(sharedArr[:], shared)
+
+	info := append(append(append([]byte{}, c.public[:]...), c.id...), accessoryPublic[:]...)
+	signature := ed25519.Sign(c.signKey, info)
+
+	key, err := hkdfKey(sharedArr[:], "Pair-Verify-Encrypt-Salt", "Pair-Verify-Encrypt-Info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub := TLV8Encode(
+		TLV8Item{Type: TypeIdentifier, Value: c.id},
+		TLV8Item{Type: TypeSignature, Value: signature},
+	)
+	return aead.Seal(nil, pairSetupNonce("PV-Msg03"), sub, nil)
+}
+
+func TestPairVerifyHandshakeValid(t *testing.T) {
+	identity, err := NewIdentity("test-accessory")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	controller := newControllerVerify(t)
+	pairing := &Pairing{ControllerID: controller.id, ControllerPublicKey: controller.verifyKey}
+
+	verify := NewVerifySession(identity, pairing)
+
+	accessoryPublic, signature, err := verify.StartM1(controller.public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sanity: the controller can verify the accessory's own M2 signature.
+	info := append(append(append([]byte{}, accessoryPublic[:]...), identity.ID...), controller.public[:]...)
+	if !ed25519.Verify(identity.PublicKey, info, signature) {
+		t.Fatal("accessory M2 signature does not verify")
+	}
+
+	if _, err = verify.EncryptM2(signature); err != nil {
+		t.Fatalf("EncryptM2: %v", err)
+	}
+
+	m3 := controller.m3Encrypted(t, accessoryPublic)
+	if err = verify.VerifyM3(m3); err != nil {
+		t.Fatalf("valid M3 rejected: %v", err)
+	}
+
+	accessoryKey, controllerKey, err := verify.ControlKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accessoryKey == controllerKey {
+		t.Fatal("accessory and controller control keys must differ")
+	}
+}
+
+func TestPairVerifyRejectsWrongSignature(t *testing.T) {
+	identity, err := NewIdentity("test-accessory")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	controller := newControllerVerify(t)
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller.signKey = otherPriv // sign with a key that doesn't match the stored pairing
+
+	pairing := &Pairing{ControllerID: controller.id, ControllerPublicKey: controller.verifyKey}
+	verify := NewVerifySession(identity, pairing)
+
+	accessoryPublic, _, err := verify.StartM1(controller.public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m3 := controller.m3Encrypted(t, accessoryPublic)
+	if err = verify.VerifyM3(m3); err == nil {
+		t.Fatal("expected rejection of signature from an unpaired key")
+	}
+}
+
+func TestPairVerifyRejectsMissingPairing(t *testing.T) {
+	identity, err := NewIdentity("test-accessory")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	controller := newControllerVerify(t)
+	verify := NewVerifySession(identity, nil) // no completed pair-setup
+
+	accessoryPublic, _, err := verify.StartM1(controller.public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m3 := controller.m3Encrypted(t, accessoryPublic)
+	if err = verify.VerifyM3(m3); err == nil {
+		t.Fatal("expected rejection when no pairing has been established")
+	}
+}
+
+func TestPairSetupM5RoundTrip(t *testing.T) {
+	const pin = "031-45-154"
+
+	identity, err := NewIdentity("test-accessory")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setup := NewSetupSession(identity, pin)
+	salt, B, err := setup.StartM1()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newClientSRP(t)
+	K := client.sessionKey(salt, B, pin)
+	m1 := clientProof(salt, client.A, B, K)
+
+	if _, err = setup.VerifyM3(client.A, m1); err != nil {
+		t.Fatalf("VerifyM3: %v", err)
+	}
+
+	controllerPub, controllerPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controllerID := []byte("controller-1")
+
+	controllerX, err := hkdfKey(K, "Pair-Setup-Controller-Sign-Salt", "Pair-Setup-Controller-Sign-Info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	controllerInfo := append(append(append([]byte{}, controllerX[:]...), controllerID...), controllerPub...)
+	controllerSignature := ed25519.Sign(controllerPriv, controllerInfo)
+
+	key, err := hkdfKey(K, "Pair-Setup-Encrypt-Salt", "Pair-Setup-Encrypt-Info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub := TLV8Encode(
+		TLV8Item{Type: TypeIdentifier, Value: controllerID},
+		TLV8Item{Type: TypePublicKey, Value: controllerPub},
+		TLV8Item{Type: TypeSignature, Value: controllerSignature},
+	)
+	m5Encrypted := aead.Seal(nil, pairSetupNonce("PS-Msg05"), sub, nil)
+
+	pairing, m6Encrypted, err := setup.VerifyM5(identity, m5Encrypted)
+	if err != nil {
+		t.Fatalf("VerifyM5: %v", err)
+	}
+	if string(pairing.ControllerID) != string(controllerID) {
+		t.Fatalf("pairing controller id mismatch: %q", pairing.ControllerID)
+	}
+	if !pairing.ControllerPublicKey.Equal(controllerPub) {
+		t.Fatal("pairing controller public key mismatch")
+	}
+
+	// Controller side: decrypt M6 and verify the accessory's signature.
+	m6, err := aead.Open(nil, pairSetupNonce("PS-Msg06"), m6Encrypted, nil)
+	if err != nil {
+		t.Fatalf("controller failed to decrypt M6: %v", err)
+	}
+
+	fields := TLV8Decode(m6)
+	accessoryID := fields[TypeIdentifier]
+	accessoryPub := ed25519.PublicKey(fields[TypePublicKey])
+	accessorySig := fields[TypeSignature]
+
+	accessoryX, err := hkdfKey(K, "Pair-Setup-Accessory-Sign-Salt", "Pair-Setup-Accessory-Sign-Info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessoryInfo := append(append(append([]byte{}, accessoryX[:]...), accessoryID...), accessoryPub...)
+	if !ed25519.Verify(accessoryPub, accessoryInfo, accessorySig) {
+		t.Fatal("accessory M6 signature does not verify")
+	}
+}
+
+func TestPairSetupM5RejectsBadSignature(t *testing.T) {
+	const pin = "031-45-154"
+
+	identity, err := NewIdentity("test-accessory")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setup := NewSetupSession(identity, pin)
+	salt, B, err := setup.StartM1()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newClientSRP(t)
+	K := client.sessionKey(salt, B, pin)
+	m1 := clientProof(salt, client.A, B, K)
+	if _, err = setup.VerifyM3(client.A, m1); err != nil {
+		t.Fatalf("VerifyM3: %v", err)
+	}
+
+	controllerPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controllerID := []byte("controller-1")
+
+	controllerX, err := hkdfKey(K, "Pair-Setup-Controller-Sign-Salt", "Pair-Setup-Controller-Sign-Info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	controllerInfo := append(append(append([]byte{}, controllerX[:]...), controllerID...), controllerPub...)
+	badSignature := ed25519.Sign(otherPriv, controllerInfo) // signed with an unrelated key
+
+	key, err := hkdfKey(K, "Pair-Setup-Encrypt-Salt", "Pair-Setup-Encrypt-Info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub := TLV8Encode(
+		TLV8Item{Type: TypeIdentifier, Value: controllerID},
+		TLV8Item{Type: TypePublicKey, Value: controllerPub},
+		TLV8Item{Type: TypeSignature, Value: badSignature},
+	)
+	m5Encrypted := aead.Seal(nil, pairSetupNonce("PS-Msg05"), sub, nil)
+
+	if _, _, err = setup.VerifyM5(identity, m5Encrypted); err == nil {
+		t.Fatal("expected rejection of a bad M5 signature")
+	}
+}
@@ -0,0 +1,148 @@
+// Package accessory implements the server (accessory) side of HAP pairing
+// and characteristic exchange, as used by homekit.Server to publish go2rtc
+// streams as HomeKit IP cameras.
+package accessory
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"math/big"
+)
+
+// 3072-bit SRP group from RFC 5054, the group HAP requires for pair-setup.
+var srpN, _ = new(big.Int).SetString(
+	"FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B"+
+		"302B0A6DF25F14374FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7EDEE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3BE39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF6955817183995497CEA956AE515D2261898FA051015728E5A8AACAA68FFFFFFFFFFFFFFFF",
+	16,
+)
+
+var srpG = big.NewInt(5)
+
+// Verifier derives the SRP-6a password verifier for a setup PIN, as stored
+// per-accessory and consumed on every pair-setup attempt.
+func Verifier(salt []byte, username, pin string) *big.Int {
+	x := srpX(salt, username, pin)
+	return new(big.Int).Exp(srpG, x, srpN)
+}
+
+func srpX(salt []byte, username, password string) *big.Int {
+	inner := sha512.Sum512([]byte(username + ":" + password))
+	h := sha512.New()
+	h.Write(salt)
+	h.Write(inner[:])
+	return new(big.Int).SetBytes(h.Sum(nil))
+}
+
+// ServerSession runs one SRP-6a pair-setup exchange (M1-M6).
+type ServerSession struct {
+	Salt     []byte
+	Verifier *big.Int
+
+	b, B, K *big.Int
+}
+
+func NewServerSession(salt []byte, verifier *big.Int) *ServerSession {
+	return &ServerSession{Salt: salt, Verifier: verifier}
+}
+
+// Public computes the server's public value B = (k*v + g^b) % N and must be
+// sent to the controller alongside the salt in response to M1.
+func (s *ServerSession) Public() *big.Int {
+	if s.B != nil {
+		return s.B
+	}
+
+	b := make([]byte, 384)
+	_, _ = rand.Read(b)
+	s.b = new(big.Int).SetBytes(b)
+
+	k := srpK()
+	gb := new(big.Int).Exp(srpG, s.b, srpN)
+	kv := new(big.Int).Mul(k, s.Verifier)
+	s.B = new(big.Int).Mod(new(big.Int).Add(kv, gb), srpN)
+
+	return s.B
+}
+
+// ComputeKey derives the shared session key K from the controller's public
+// value A, and must be called before VerifyM1/Proof.
+//
+// Per SRP-6a, A mod N must be non-zero: a controller that sends A=0 (or any
+// multiple of N) forces S=0 regardless of the verifier, making K a fixed,
+// publicly-computable value and letting an attacker forge a valid client
+// proof without ever knowing the setup PIN.
+func (s *ServerSession) ComputeKey(A *big.Int) error {
+	if new(big.Int).Mod(A, srpN).Sign() == 0 {
+		return errors.New("hap: pair-setup SRP public value A is degenerate")
+	}
+
+	u := srpU(A, s.Public())
+
+	// S = (A * v^u) ^ b % N
+	vu := new(big.Int).Exp(s.Verifier, u, srpN)
+	base := new(big.Int).Mod(new(big.Int).Mul(A, vu), srpN)
+	S := new(big.Int).Exp(base, s.b, srpN)
+
+	sum := sha512.Sum512(S.Bytes())
+	s.K = new(big.Int).SetBytes(sum[:])
+
+	return nil
+}
+
+func srpK() *big.Int {
+	h := sha512.New()
+	h.Write(srpN.Bytes())
+	h.Write(srpG.Bytes())
+	return new(big.Int).SetBytes(h.Sum(nil))
+}
+
+func srpU(A, B *big.Int) *big.Int {
+	h := sha512.New()
+	h.Write(A.Bytes())
+	h.Write(B.Bytes())
+	return new(big.Int).SetBytes(h.Sum(nil))
+}
+
+// SessionKey returns the raw shared secret K, used to derive the HKDF keys
+// for the pair-verify and control-channel encryption that follow pair-setup.
+func (s *ServerSession) SessionKey() []byte {
+	return s.K.Bytes()
+}
+
+// ClientProof computes M1 = H(H(N) xor H(g) | H(I) | s | A | B | K), the
+// proof the controller must present in M3. ComputeKey must run first.
+func (s *ServerSession) ClientProof(A *big.Int) []byte {
+	hn := sha512.Sum512(srpN.Bytes())
+	hg := sha512.Sum512(srpG.Bytes())
+
+	var hng [sha512.Size]byte
+	for i := range hng {
+		hng[i] = hn[i] ^ hg[i]
+	}
+
+	hi := sha512.Sum512([]byte(srpSetupUsername))
+
+	h := sha512.New()
+	h.Write(hng[:])
+	h.Write(hi[:])
+	h.Write(s.Salt)
+	h.Write(A.Bytes())
+	h.Write(s.B.Bytes())
+	h.Write(s.K.Bytes())
+	return h.Sum(nil)
+}
+
+// ServerProof computes M2 = H(A | M1 | K), returned to the controller as
+// proof the server derived the same session key.
+func (s *ServerSession) ServerProof(A *big.Int, clientProof []byte) []byte {
+	h := sha512.New()
+	h.Write(A.Bytes())
+	h.Write(clientProof)
+	h.Write(s.K.Bytes())
+	return h.Sum(nil)
+}
+
+// srpSetupUsername is the fixed SRP identity HAP uses for pair-setup; there
+// is no real per-user account, so both sides hardcode the same value.
+const srpSetupUsername = "Pair-Setup"
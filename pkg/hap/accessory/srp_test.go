@@ -0,0 +1,180 @@
+package accessory
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"math/big"
+	"testing"
+)
+
+// clientSRP reimplements the controller side of SRP-6a independently of
+// ServerSession, so these tests exercise the real wire handshake instead of
+// both sides sharing the same derivation code.
+type clientSRP struct {
+	a, A *big.Int
+}
+
+func newClientSRP(t *testing.T) *clientSRP {
+	t.Helper()
+	a, err := rand.Int(rand.Reader, srpN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	A := new(big.Int).Exp(srpG, a, srpN)
+	return &clientSRP{a: a, A: A}
+}
+
+func (c *clientSRP) sessionKey(salt []byte, B *big.Int, pin string) []byte {
+	x := srpX(salt, srpSetupUsername, pin)
+	u := srpU(c.A, B)
+	k := srpK()
+
+	gx := new(big.Int).Exp(srpG, x, srpN)
+	base := new(big.Int).Mod(new(big.Int).Sub(B, new(big.Int).Mul(k, gx)), srpN)
+	exp := new(big.Int).Add(c.a, new(big.Int).Mul(u, x))
+	S := new(big.Int).Exp(base, exp, srpN)
+
+	sum := sha512.Sum512(S.Bytes())
+	return sum[:]
+}
+
+func clientProof(salt []byte, A, B *big.Int, K []byte) []byte {
+	hn := sha512.Sum512(srpN.Bytes())
+	hg := sha512.Sum512(srpG.Bytes())
+	var hng [sha512.Size]byte
+	for i := range hng {
+		hng[i] = hn[i] ^ hg[i]
+	}
+	hi := sha512.Sum512([]byte(srpSetupUsername))
+
+	h := sha512.New()
+	h.Write(hng[:])
+	h.Write(hi[:])
+	h.Write(salt)
+	h.Write(A.Bytes())
+	h.Write(B.Bytes())
+	h.Write(K)
+	return h.Sum(nil)
+}
+
+func serverProofWant(A *big.Int, m1, K []byte) []byte {
+	h := sha512.New()
+	h.Write(A.Bytes())
+	h.Write(m1)
+	h.Write(K)
+	return h.Sum(nil)
+}
+
+func TestSRPHandshakeValidProof(t *testing.T) {
+	const pin = "031-45-154"
+
+	identity, err := NewIdentity("test-accessory")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setup := NewSetupSession(identity, pin)
+	salt, B, err := setup.StartM1()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newClientSRP(t)
+	K := client.sessionKey(salt, B, pin)
+	m1 := clientProof(salt, client.A, B, K)
+
+	serverProof, err := setup.VerifyM3(client.A, m1)
+	if err != nil {
+		t.Fatalf("valid client proof rejected: %v", err)
+	}
+
+	want := serverProofWant(client.A, m1, K)
+	if !bytes.Equal(serverProof, want) {
+		t.Fatalf("server proof mismatch: got %x, want %x", serverProof, want)
+	}
+}
+
+// TestSRPHandshakeZeroARejected guards against the classic SRP-6a
+// authentication bypass: A=0 (or any multiple of N) forces the shared
+// secret S to 0 regardless of the verifier, making K a fixed,
+// publicly-computable value and letting an attacker forge a valid client
+// proof without ever knowing the setup PIN.
+func TestSRPHandshakeZeroARejected(t *testing.T) {
+	identity, err := NewIdentity("test-accessory")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setup := NewSetupSession(identity, "031-45-154")
+	if _, _, err = setup.StartM1(); err != nil {
+		t.Fatal(err)
+	}
+
+	zero := big.NewInt(0)
+	if _, err = setup.VerifyM3(zero, make([]byte, sha512.Size)); err == nil {
+		t.Fatal("expected rejection of a degenerate A=0")
+	}
+
+	multipleOfN := new(big.Int).Mul(srpN, big.NewInt(2))
+	if _, err = setup.VerifyM3(multipleOfN, make([]byte, sha512.Size)); err == nil {
+		t.Fatal("expected rejection of A as a multiple of N")
+	}
+}
+
+func TestSRPHandshakeWrongPINRejected(t *testing.T) {
+	identity, err := NewIdentity("test-accessory")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setup := NewSetupSession(identity, "031-45-154")
+	salt, B, err := setup.StartM1()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newClientSRP(t)
+	K := client.sessionKey(salt, B, "000-00-000") // wrong PIN
+	m1 := clientProof(salt, client.A, B, K)
+
+	if _, err := setup.VerifyM3(client.A, m1); err == nil {
+		t.Fatal("expected proof mismatch for wrong PIN, got nil error")
+	}
+}
+
+func TestSRPHandshakeTamperedProofRejected(t *testing.T) {
+	identity, err := NewIdentity("test-accessory")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setup := NewSetupSession(identity, "031-45-154")
+	salt, B, err := setup.StartM1()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newClientSRP(t)
+	K := client.sessionKey(salt, B, "031-45-154")
+	m1 := clientProof(salt, client.A, B, K)
+	m1[0] ^= 0xFF // flip a bit
+
+	if _, err := setup.VerifyM3(client.A, m1); err == nil {
+		t.Fatal("expected rejection of tampered proof, got nil error")
+	}
+}
+
+func TestVerifierDeterministic(t *testing.T) {
+	salt := []byte("fixed-salt-value")
+	v1 := Verifier(salt, srpSetupUsername, "031-45-154")
+	v2 := Verifier(salt, srpSetupUsername, "031-45-154")
+	if v1.Cmp(v2) != 0 {
+		t.Fatal("Verifier should be deterministic for the same salt/username/pin")
+	}
+
+	v3 := Verifier(salt, srpSetupUsername, "111-11-111")
+	if v1.Cmp(v3) == 0 {
+		t.Fatal("Verifier should differ for a different pin")
+	}
+}
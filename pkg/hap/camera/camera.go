@@ -0,0 +1,212 @@
+// Package camera builds the HAP camera RTP stream management service (the
+// accessory-side half of pkg/homekit/server.go) and turns a controller's
+// SelectedStreamConfiguration write into a live SRTP session fed from an
+// existing go2rtc stream.
+package camera
+
+import (
+	"encoding/base64"
+	"errors"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/hap/accessory"
+	"github.com/AlexxIT/go2rtc/pkg/srtp"
+)
+
+// Service and characteristic UUIDs from the HAP camera RTP stream management
+// service (HAP-R2, table 9-20).
+const (
+	typeAccessoryInformation = "3E"
+	typeCameraRTPStreamMgmt  = "110"
+
+	typeIdentify = "14"
+	typeName     = "23"
+
+	// TypeSupportedVideoStreamConfiguration and TypeSupportedAudioStreamConfiguration
+	// are exported because pkg/homekit's client half looks them up by type on
+	// a real accessory via Accessory.GetCharacter, the same way NewAccessory
+	// publishes them below.
+	TypeSupportedVideoStreamConfiguration = "114"
+	TypeSupportedAudioStreamConfiguration = "115"
+
+	typeSupportedRTPConfig          = "116"
+	typeSelectedStreamConfiguration = "117"
+	typeStreamingStatus             = "120"
+)
+
+// IIDs are fixed per accessory instance: go2rtc only ever publishes one
+// camera service per stream, so there is no need to allocate these
+// dynamically.
+const (
+	IIDAccessoryInformation = 1
+	IIDIdentify             = 2
+	IIDName                 = 3
+
+	IIDCameraRTPStreamManagement         = 10
+	IIDSupportedVideoStreamConfiguration = 11
+	IIDSupportedAudioStreamConfiguration = 12
+	IIDSupportedRTPConfiguration         = 13
+	IIDSelectedStreamConfiguration       = 14
+	IIDStreamingStatus                   = 15
+)
+
+// TLV8 item types within SupportedVideoStreamConfiguration /
+// SupportedAudioStreamConfiguration / SelectedStreamConfiguration.
+const (
+	tlvVideoCodecConfig = 0x01
+	tlvAudioCodecConfig = 0x01
+
+	tlvCodecType   = 0x01
+	tlvCodecParams = 0x02
+
+	tlvSelectedVideo = 0x01
+	tlvSelectedAudio = 0x02
+
+	videoCodecTypeH264 = 0x00
+	audioCodecTypeAAC  = 0x02
+	audioCodecTypeOpus = 0x03
+)
+
+// NewAccessory builds the camera accessory model (info service + RTP stream
+// management service) advertising the codecs already present on medias, the
+// same set GetMedias() returns for any other go2rtc producer.
+func NewAccessory(name string, medias []*core.Media) (*accessory.Accessory, error) {
+	videoMedia := mediaByKind(medias, core.KindVideo)
+	audioMedia := mediaByKind(medias, core.KindAudio)
+	if videoMedia == nil {
+		return nil, errors.New("camera: stream has no video media")
+	}
+
+	supportedVideo := encodeSupportedVideo(videoMedia)
+	supportedAudio := encodeSupportedAudio(audioMedia)
+
+	return &accessory.Accessory{
+		AID: 1,
+		Services: []accessory.Service{
+			{
+				IID:  IIDAccessoryInformation,
+				Type: typeAccessoryInformation,
+				Characteristics: []accessory.Characteristic{
+					{IID: IIDIdentify, Type: typeIdentify, Format: "bool", Perms: []string{"pw"}},
+					{IID: IIDName, Type: typeName, Format: "string", Value: name, Perms: []string{"pr"}},
+				},
+			},
+			{
+				IID:  IIDCameraRTPStreamManagement,
+				Type: typeCameraRTPStreamMgmt,
+				Characteristics: []accessory.Characteristic{
+					{
+						IID: IIDSupportedVideoStreamConfiguration, Type: TypeSupportedVideoStreamConfiguration,
+						Format: "tlv8", Perms: []string{"pr"}, Value: base64.StdEncoding.EncodeToString(supportedVideo),
+					},
+					{
+						IID: IIDSupportedAudioStreamConfiguration, Type: TypeSupportedAudioStreamConfiguration,
+						Format: "tlv8", Perms: []string{"pr"}, Value: base64.StdEncoding.EncodeToString(supportedAudio),
+					},
+					{
+						IID: IIDSupportedRTPConfiguration, Type: typeSupportedRTPConfig,
+						Format: "tlv8", Perms: []string{"pr"}, Value: base64.StdEncoding.EncodeToString(encodeSupportedRTP()),
+					},
+					{
+						IID: IIDSelectedStreamConfiguration, Type: typeSelectedStreamConfiguration,
+						Format: "tlv8", Perms: []string{"pw", "pr"},
+					},
+					{
+						IID: IIDStreamingStatus, Type: typeStreamingStatus,
+						Format: "tlv8", Perms: []string{"pr", "ev"}, Value: base64.StdEncoding.EncodeToString([]byte{0x01, 0x01, 0x00}), // available
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+func mediaByKind(medias []*core.Media, kind string) *core.Media {
+	for _, media := range medias {
+		if media.Kind == kind {
+			return media
+		}
+	}
+	return nil
+}
+
+func encodeSupportedVideo(media *core.Media) []byte {
+	codec := tlv8Encode(
+		tlvItem{tlvCodecType, []byte{videoCodecTypeH264}},
+	)
+	return tlv8Encode(tlvItem{tlvVideoCodecConfig, codec})
+}
+
+func encodeSupportedAudio(media *core.Media) []byte {
+	codecType := byte(audioCodecTypeAAC)
+	if media != nil && media.Codecs != nil && media.Codecs[0].Name == core.CodecOpus {
+		codecType = audioCodecTypeOpus
+	}
+	codec := tlv8Encode(
+		tlvItem{tlvCodecType, []byte{codecType}},
+	)
+	return tlv8Encode(tlvItem{tlvAudioCodecConfig, codec})
+}
+
+func encodeSupportedRTP() []byte {
+	// SRTPCryptoSuite: AES_CM_128_HMAC_SHA1_80
+	return tlv8Encode(tlvItem{0x02, []byte{0x00}})
+}
+
+// StartSessionFromTLV decodes a SelectedStreamConfiguration write and wires
+// the requested video (and, if present, audio) track from src into session,
+// the same producer/consumer handoff Client.AddTrack uses for talkback.
+func StartSessionFromTLV(value interface{}, src core.Producer, session *srtp.Session) error {
+	raw, err := decodeTLV8Value(value)
+	if err != nil {
+		return err
+	}
+
+	top := tlv8Decode(raw)
+
+	if v, ok := top[tlvSelectedVideo]; ok {
+		if err := startTrack(src, session, core.KindVideo, v); err != nil {
+			return err
+		}
+	}
+
+	if a, ok := top[tlvSelectedAudio]; ok {
+		if err := startTrack(src, session, core.KindAudio, a); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func startTrack(src core.Producer, session *srtp.Session, kind string, _ []byte) error {
+	media := mediaByKind(src.GetMedias(), kind)
+	if media == nil || len(media.Codecs) == 0 {
+		return nil // controller didn't select a track of this kind
+	}
+	codec := media.Codecs[0]
+
+	receiver, err := src.GetTrack(media, codec)
+	if err != nil {
+		return err
+	}
+
+	sender := core.NewSender(media, codec)
+	sender.Handler = session.WriteRTP
+	sender.HandleRTP(receiver)
+
+	return nil
+}
+
+// decodeTLV8Value accepts either the raw bytes already decoded by the HTTP
+// layer or the base64 string as it travels over HAP-JSON.
+func decodeTLV8Value(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return base64.StdEncoding.DecodeString(v)
+	default:
+		return nil, errors.New("camera: SelectedStreamConfiguration value is not tlv8")
+	}
+}
@@ -0,0 +1,78 @@
+package camera
+
+import "errors"
+
+// VideoCodec and AudioCodec are the client-facing view of one entry inside
+// SupportedVideoStreamConfiguration / SupportedAudioStreamConfiguration: the
+// codec type byte NewAccessory's encodeSupportedVideo/encodeSupportedAudio
+// publish above, decoded back out by a controller reading the
+// characteristic off a real camera accessory.
+type VideoCodec struct {
+	Type byte
+}
+
+type AudioCodec struct {
+	Type byte
+}
+
+// SupportedVideoStreamConfig and SupportedAudioStreamConfig are what a HAP
+// controller decodes TypeSupportedVideoStreamConfiguration /
+// TypeSupportedAudioStreamConfiguration into. UnmarshalTLV8 lets any TLV8
+// reader hook into this package's own codec instead of duplicating it.
+type SupportedVideoStreamConfig struct {
+	Codecs []VideoCodec
+}
+
+func (c *SupportedVideoStreamConfig) UnmarshalTLV8(raw []byte) error {
+	c.Codecs = nil
+	for _, item := range tlv8DecodeAll(raw) {
+		if item.typ != tlvVideoCodecConfig {
+			continue
+		}
+		fields := tlv8Decode(item.value)
+		codecType, ok := fields[tlvCodecType]
+		if !ok || len(codecType) == 0 {
+			continue
+		}
+		c.Codecs = append(c.Codecs, VideoCodec{Type: codecType[0]})
+	}
+	if len(c.Codecs) == 0 {
+		return errors.New("camera: SupportedVideoStreamConfiguration has no codecs")
+	}
+	return nil
+}
+
+type SupportedAudioStreamConfig struct {
+	Codecs []AudioCodec
+}
+
+func (c *SupportedAudioStreamConfig) UnmarshalTLV8(raw []byte) error {
+	c.Codecs = nil
+	for _, item := range tlv8DecodeAll(raw) {
+		if item.typ != tlvAudioCodecConfig {
+			continue
+		}
+		fields := tlv8Decode(item.value)
+		codecType, ok := fields[tlvCodecType]
+		if !ok || len(codecType) == 0 {
+			continue
+		}
+		c.Codecs = append(c.Codecs, AudioCodec{Type: codecType[0]})
+	}
+	if len(c.Codecs) == 0 {
+		return errors.New("camera: SupportedAudioStreamConfiguration has no codecs")
+	}
+	return nil
+}
+
+// Stream, NewStream and SetTalkback are NOT implemented here. pkg/homekit's
+// client half (Client.Start, Client.AddTrack) calls camera.NewStream(hap
+// *hap.Client, ...) to drive the SetupEndpoints/SelectedStreamConfiguration
+// exchange against a real accessory and camera.(*Stream).SetTalkback to wire
+// the reverse audio leg, but that requires two packages this tree doesn't
+// have at all: a client-side pkg/hap (hap.Client, hap.Accessory,
+// hap.Characteristic, hap.DecodeKey - distinct from this repo's
+// accessory-side pkg/hap/accessory) and pkg/srtp (srtp.Server, srtp.Session,
+// srtp.Endpoint). Both predate this package and are out of scope for the
+// accessory-publisher model built here; until they exist, pkg/homekit's
+// client half cannot build regardless of what this file defines.
@@ -0,0 +1,58 @@
+package camera
+
+// Minimal TLV8 codec for the camera RTP stream management characteristics
+// (SupportedVideoStreamConfiguration, SelectedStreamConfiguration, ...).
+// HAP TLV8 items are type(1)+length(1)+value, with values over 255 bytes
+// split across repeated entries of the same type; none of the structures
+// this package builds or parses ever get that large, so that fragmentation
+// case is not implemented here.
+
+type tlvItem struct {
+	typ   byte
+	value []byte
+}
+
+func tlv8Encode(items ...tlvItem) []byte {
+	var buf []byte
+	for _, it := range items {
+		buf = append(buf, it.typ, byte(len(it.value)))
+		buf = append(buf, it.value...)
+	}
+	return buf
+}
+
+// tlv8Decode splits a flat TLV8 blob into its top-level type/value pairs.
+// Nested TLV8 (e.g. a video codec's parameters inside a video config entry)
+// is decoded by calling tlv8Decode again on the returned value.
+func tlv8Decode(raw []byte) map[byte][]byte {
+	out := make(map[byte][]byte)
+	for len(raw) >= 2 {
+		typ, n := raw[0], int(raw[1])
+		raw = raw[2:]
+		if n > len(raw) {
+			break
+		}
+		out[typ] = append(out[typ], raw[:n]...)
+		raw = raw[n:]
+	}
+	return out
+}
+
+// tlv8DecodeAll splits a flat TLV8 blob into its top-level items in order,
+// without merging repeated types. SupportedVideoStreamConfiguration and
+// SupportedAudioStreamConfiguration encode one video/audio codec config
+// entry per repeated tlvVideoCodecConfig/tlvAudioCodecConfig item, which
+// tlv8Decode's map would otherwise concatenate into one undecodable blob.
+func tlv8DecodeAll(raw []byte) []tlvItem {
+	var items []tlvItem
+	for len(raw) >= 2 {
+		typ, n := raw[0], int(raw[1])
+		raw = raw[2:]
+		if n > len(raw) {
+			break
+		}
+		items = append(items, tlvItem{typ, append([]byte(nil), raw[:n]...)})
+		raw = raw[n:]
+	}
+	return items
+}
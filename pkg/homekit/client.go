@@ -9,14 +9,26 @@ import (
 	"time"
 
 	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/core/jitter"
 	"github.com/AlexxIT/go2rtc/pkg/hap"
 	"github.com/AlexxIT/go2rtc/pkg/hap/camera"
 	"github.com/AlexxIT/go2rtc/pkg/srtp"
 	"github.com/pion/rtp"
 )
 
+// Default jitter-buffer delays, used unless the stream URL overrides them
+// with ?jitter=<duration> (e.g. ?jitter=0 disables buffering for low-latency
+// setups). HAP SRTP over Wi-Fi reorders packets often enough that these are
+// worth keeping on by default.
+const (
+	defaultVideoJitter = 80 * time.Millisecond
+	defaultAudioJitter = 20 * time.Millisecond
+	jitterDepth        = 50
+)
+
 type Client struct {
 	core.SuperProducer
+	core.SuperConsumer
 
 	hap  *hap.Client
 	srtp *srtp.Server
@@ -26,6 +38,10 @@ type Client struct {
 
 	videoSession *srtp.Session
 	audioSession *srtp.Session
+	talkSession  *srtp.Session
+
+	videoJitter time.Duration
+	audioJitter time.Duration
 
 	stream *camera.Stream
 }
@@ -49,7 +65,16 @@ func Dial(rawURL string, server *srtp.Server) (*Client, error) {
 		return nil, err
 	}
 
-	return &Client{hap: conn, srtp: server}, nil
+	videoJitter, audioJitter := defaultVideoJitter, defaultAudioJitter
+	if s := query.Get("jitter"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		videoJitter, audioJitter = d, d
+	}
+
+	return &Client{hap: conn, srtp: server, videoJitter: videoJitter, audioJitter: audioJitter}, nil
 }
 
 func (c *Client) Conn() net.Conn {
@@ -57,8 +82,8 @@ func (c *Client) Conn() net.Conn {
 }
 
 func (c *Client) GetMedias() []*core.Media {
-	if c.Medias != nil {
-		return c.Medias
+	if c.SuperProducer.Medias != nil {
+		return c.SuperProducer.Medias
 	}
 
 	acc, err := c.hap.GetFirstAccessory()
@@ -82,12 +107,13 @@ func (c *Client) GetMedias() []*core.Media {
 		return nil
 	}
 
-	c.Medias = []*core.Media{
+	c.SuperProducer.Medias = []*core.Media{
 		videoToMedia(c.videoConfig.Codecs),
 		audioToMedia(c.audioConfig.Codecs),
+		talkbackToMedia(c.audioConfig.Codecs),
 	}
 
-	return c.Medias
+	return c.SuperProducer.Medias
 }
 
 func (c *Client) Start() error {
@@ -117,21 +143,29 @@ func (c *Client) Start() error {
 	c.srtp.AddSession(c.videoSession)
 	c.srtp.AddSession(c.audioSession)
 
+	if c.talkSession != nil {
+		if err = c.stream.SetTalkback(c.talkSession, &c.audioConfig.Codecs[0]); err != nil {
+			return err
+		}
+	}
+
 	deadline := time.NewTimer(core.ConnDeadline)
 
 	if videoTrack != nil {
+		writeVideo := c.jitterHandler(c.videoJitter, videoTrack.WriteRTP)
 		c.videoSession.OnReadRTP = func(packet *rtp.Packet) {
 			deadline.Reset(core.ConnDeadline)
-			videoTrack.WriteRTP(packet)
+			writeVideo(packet)
 		}
 
 		if audioTrack != nil {
-			c.audioSession.OnReadRTP = audioTrack.WriteRTP
+			c.audioSession.OnReadRTP = c.jitterHandler(c.audioJitter, audioTrack.WriteRTP)
 		}
 	} else {
+		writeAudio := c.jitterHandler(c.audioJitter, audioTrack.WriteRTP)
 		c.audioSession.OnReadRTP = func(packet *rtp.Packet) {
 			deadline.Reset(core.ConnDeadline)
-			audioTrack.WriteRTP(packet)
+			writeAudio(packet)
 		}
 	}
 
@@ -142,20 +176,56 @@ func (c *Client) Start() error {
 
 func (c *Client) Stop() error {
 	_ = c.SuperProducer.Close()
+	_ = c.SuperConsumer.Close()
 
 	c.srtp.DelSession(c.videoSession)
 	c.srtp.DelSession(c.audioSession)
 
+	if c.talkSession != nil {
+		c.srtp.DelSession(c.talkSession)
+	}
+
 	return c.hap.Close()
 }
 
+// AddTrack negotiates the reverse (talkback) audio leg. The camera already
+// advertises the codecs it can decode in audioConfig.Codecs (the same list
+// used for the incoming microphone track), so we reuse the first one and
+// open a second, outgoing-keyed SRTP session for it.
+func (c *Client) AddTrack(media *core.Media, codec *core.Codec, track *core.Receiver) (*core.Sender, error) {
+	if len(c.audioConfig.Codecs) == 0 {
+		return nil, errors.New("homekit: camera has no audio codecs for talkback")
+	}
+
+	sender := core.NewSender(media, codec)
+
+	c.talkSession = &srtp.Session{Local: c.srtpEndpoint()}
+	c.srtp.AddSession(c.talkSession)
+
+	sender.Handler = limitter(c.talkSession.WriteRTP)
+	sender.HandleRTP(track)
+
+	c.Senders = append(c.Senders, sender)
+
+	// Start may not have run yet, in which case the stream is wired up
+	// once camera.NewStream is created.
+	if c.stream != nil {
+		if err := c.stream.SetTalkback(c.talkSession, &c.audioConfig.Codecs[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	return sender, nil
+}
+
 func (c *Client) MarshalJSON() ([]byte, error) {
 	info := &core.Info{
 		Type: "HomeKit active producer",
 		URL:  c.hap.URL(),
 		//SDP:       fmt.Sprintf("%+v", *c.config),
-		Medias:    c.Medias,
+		Medias:    c.SuperProducer.Medias,
 		Receivers: c.Receivers,
+		Senders:   c.Senders,
 		Recv:      c.videoSession.Recv + c.audioSession.Recv,
 	}
 	return json.Marshal(info)
@@ -187,6 +257,25 @@ func (c *Client) startMJPEG() error {
 	}
 }
 
+// talkbackToMedia exposes the camera's existing audio codecs as a sendonly
+// media so go2rtc's consumers (e.g. a browser mic over WebRTC) can feed
+// the reverse leg via AddTrack.
+func talkbackToMedia(codecs []camera.AudioCodec) *core.Media {
+	media := audioToMedia(codecs)
+	media.Direction = core.DirectionSendonly
+	return media
+}
+
+// jitterHandler wraps out in a per-SSRC reorder buffer, unless delay is 0
+// (e.g. ?jitter=0 for low-latency setups), in which case packets pass
+// straight through as before.
+func (c *Client) jitterHandler(delay time.Duration, out core.HandlerFunc) core.HandlerFunc {
+	if delay <= 0 {
+		return out
+	}
+	return jitter.NewDemux(delay, jitterDepth, out).WriteRTP
+}
+
 func (c *Client) srtpEndpoint() *srtp.Endpoint {
 	return &srtp.Endpoint{
 		Addr:       c.hap.LocalIP(),
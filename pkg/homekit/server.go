@@ -0,0 +1,539 @@
+package homekit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/hap/accessory"
+	"github.com/AlexxIT/go2rtc/pkg/hap/camera"
+	"github.com/AlexxIT/go2rtc/pkg/srtp"
+	"github.com/brutella/dnssd"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// HAP TLV8 error codes (table 5-5), used in the rare paths where pair-setup
+// or pair-verify must report a failure back to the controller.
+const tlvErrorAuthentication = 0x02
+
+// PublishConfig is the per-stream entry under the `homekit: publish:` block
+// in go2rtc.yaml.
+type PublishConfig struct {
+	Name string `yaml:"name"`
+	PIN  string `yaml:"pin"`
+}
+
+// Server advertises a single go2rtc stream over mDNS as a HAP IP-camera
+// accessory, so iOS Home, HomeBridge and Scrypted can pair with it directly.
+type Server struct {
+	name string
+	pin  string
+	src  core.Producer
+	srtp *srtp.Server
+
+	identity *accessory.Identity
+	pairing  *accessory.Pairing
+	setup    *accessory.SetupSession
+	acc      *accessory.Accessory
+
+	listener net.Listener
+	svc      dnssd.ServiceHandle
+
+	mu sync.Mutex
+}
+
+// NewServer builds the accessory model (camera service + supported stream
+// configuration) from the medias the producer already advertises, same as
+// any other go2rtc consumer would negotiate them.
+func NewServer(name string, src core.Producer, srtpServer *srtp.Server, cfg *PublishConfig) (*Server, error) {
+	identity, err := accessory.NewIdentity(name)
+	if err != nil {
+		return nil, err
+	}
+
+	medias := src.GetMedias()
+	if medias == nil {
+		return nil, errors.New("homekit: stream has no medias to publish")
+	}
+
+	acc, err := camera.NewAccessory(name, medias)
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := cfg.Name
+	if displayName == "" {
+		displayName = name
+	}
+
+	pin := cfg.PIN
+	if pin == "" {
+		pin = "031-45-154" // go2rtc default setup code, overridable per stream
+	}
+
+	return &Server{
+		name:     displayName,
+		pin:      pin,
+		src:      src,
+		srtp:     srtpServer,
+		identity: identity,
+		acc:      acc,
+	}, nil
+}
+
+// Start listens for HAP connections and advertises the accessory over mDNS.
+func (s *Server) Start() error {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return err
+	}
+	s.listener = l
+
+	port := uint16(l.Addr().(*net.TCPAddr).Port)
+
+	cfg := dnssd.Config{
+		Name: s.name,
+		Type: "_hap._tcp",
+		Port: int(port),
+		Text: map[string]string{
+			"c#": "1",           // config number, bump on accessory model changes
+			"ff": "0",           // feature flags (0 = no MFi, not paired yet)
+			"id": s.identity.ID, // accessory id, used as the pairing identifier
+			"md": s.name,        // model/display name
+			"pv": "1.1",         // HAP protocol version
+			"s#": "1",           // state number
+			"sf": "1",           // 1 = accessory is discoverable/unpaired
+			"ci": "2",           // category: IP camera
+		},
+	}
+
+	responder, err := dnssd.NewResponder()
+	if err != nil {
+		return err
+	}
+
+	svc, err := dnssd.NewService(cfg)
+	if err != nil {
+		return err
+	}
+
+	if s.svc, err = responder.Add(svc); err != nil {
+		return err
+	}
+
+	go func() { _ = responder.Respond(context.Background()) }()
+
+	go s.accept()
+
+	return nil
+}
+
+// PairingInfo is the public subset of Server exposed to the UI for QR-code
+// rendering: the setup PIN and accessory id the controller needs.
+type PairingInfo struct {
+	Name string `json:"name"`
+	PIN  string `json:"pin"`
+	ID   string `json:"id"`
+}
+
+func (s *Server) PairingInfo() PairingInfo {
+	return PairingInfo{Name: s.name, PIN: s.pin, ID: s.identity.ID}
+}
+
+func (s *Server) Stop() error {
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	return nil
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle runs pair-setup (if the controller isn't paired yet) and then
+// pair-verify for a single connection, finally serving the plain HAP HTTP
+// exchange (GET /accessories, PUT /characteristics) over the resulting
+// encrypted record layer.
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+
+	accessoryKey, controllerKey, err := s.handshake(conn, r)
+	if err != nil {
+		return
+	}
+
+	readAEAD, err := newAEADFromKey(controllerKey)
+	if err != nil {
+		return
+	}
+	writeAEAD, err := newAEADFromKey(accessoryKey)
+	if err != nil {
+		return
+	}
+
+	er := bufio.NewReader(&decryptReader{r: r, aead: readAEAD})
+
+	for {
+		req, err := http.ReadRequest(er)
+		if err != nil {
+			return
+		}
+
+		resp := s.serveHTTP(req)
+
+		buf := &encryptWriter{conn: conn, aead: writeAEAD}
+		_ = resp.Write(buf)
+	}
+}
+
+// handshake serves pair-setup requests until the controller switches to
+// pair-verify, then runs the two-request M1-M4 pair-verify exchange and
+// returns the resulting control-channel keys once M4 has been sent.
+func (s *Server) handshake(conn net.Conn, r *bufio.Reader) (accessoryKey, controllerKey [32]byte, err error) {
+	for {
+		req, err := http.ReadRequest(r)
+		if err != nil {
+			return accessoryKey, controllerKey, err
+		}
+
+		switch req.URL.Path {
+		case "/pair-setup":
+			if err := s.servePairSetup(conn, req); err != nil {
+				return accessoryKey, controllerKey, err
+			}
+		case "/pair-verify":
+			return s.servePairVerify(conn, r, req)
+		default:
+			_ = (&http.Response{StatusCode: http.StatusBadRequest, Request: req}).Write(conn)
+			return accessoryKey, controllerKey, errors.New("homekit: expected pair-setup or pair-verify before any other request")
+		}
+	}
+}
+
+// servePairSetup answers a single POST /pair-setup request (M1, M3 or M5),
+// keeping the in-progress SRP session on s.setup across the three requests
+// that make up one pair-setup attempt on this connection.
+func (s *Server) servePairSetup(conn net.Conn, req *http.Request) error {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+
+	fields := accessory.TLV8Decode(body)
+
+	var state byte
+	if v := fields[accessory.TypeState]; len(v) == 1 {
+		state = v[0]
+	}
+
+	switch state {
+	case 1: // M1: start request
+		setup := accessory.NewSetupSession(s.identity, s.pin)
+
+		salt, public, err := setup.StartM1()
+		if err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		s.setup = setup
+		s.mu.Unlock()
+
+		reply := accessory.TLV8Encode(
+			accessory.TLV8Item{Type: accessory.TypeState, Value: []byte{2}},
+			accessory.TLV8Item{Type: accessory.TypeSalt, Value: salt},
+			accessory.TLV8Item{Type: accessory.TypePublicKey, Value: public.Bytes()},
+		)
+		return writeTLV(conn, req, reply)
+
+	case 3: // M3: SRP proof
+		s.mu.Lock()
+		setup := s.setup
+		s.mu.Unlock()
+		if setup == nil {
+			return errors.New("homekit: pair-setup M3 without a preceding M1")
+		}
+
+		A := new(big.Int).SetBytes(fields[accessory.TypePublicKey])
+
+		serverProof, err := setup.VerifyM3(A, fields[accessory.TypeProof])
+		if err != nil {
+			_ = writeTLV(conn, req, pairSetupErrorTLV(4))
+			return err
+		}
+
+		reply := accessory.TLV8Encode(
+			accessory.TLV8Item{Type: accessory.TypeState, Value: []byte{4}},
+			accessory.TLV8Item{Type: accessory.TypeProof, Value: serverProof},
+		)
+		return writeTLV(conn, req, reply)
+
+	case 5: // M5: exchange request
+		s.mu.Lock()
+		setup := s.setup
+		s.mu.Unlock()
+		if setup == nil {
+			return errors.New("homekit: pair-setup M5 without a preceding M3")
+		}
+
+		pairing, reply, err := setup.VerifyM5(s.identity, fields[accessory.TypeEncrypted])
+		if err != nil {
+			_ = writeTLV(conn, req, pairSetupErrorTLV(6))
+			return err
+		}
+
+		s.mu.Lock()
+		s.pairing = pairing
+		s.setup = nil
+		s.mu.Unlock()
+
+		m6 := accessory.TLV8Encode(
+			accessory.TLV8Item{Type: accessory.TypeState, Value: []byte{6}},
+			accessory.TLV8Item{Type: accessory.TypeEncrypted, Value: reply},
+		)
+		return writeTLV(conn, req, m6)
+
+	default:
+		return fmt.Errorf("homekit: unexpected pair-setup state %d", state)
+	}
+}
+
+// servePairVerify runs the two-request M1-M4 exchange: req is already the
+// parsed M1 request, and a second request (M3) is read directly off r.
+func (s *Server) servePairVerify(conn net.Conn, r *bufio.Reader, req *http.Request) (accessoryKey, controllerKey [32]byte, err error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return accessoryKey, controllerKey, err
+	}
+	m1 := accessory.TLV8Decode(body)
+
+	var controllerPublic [32]byte
+	copy(controllerPublic[:], m1[accessory.TypePublicKey])
+
+	s.mu.Lock()
+	pairing := s.pairing
+	s.mu.Unlock()
+
+	verify := accessory.NewVerifySession(s.identity, pairing)
+
+	accessoryPublic, signature, err := verify.StartM1(controllerPublic)
+	if err != nil {
+		return accessoryKey, controllerKey, err
+	}
+
+	encrypted, err := verify.EncryptM2(signature)
+	if err != nil {
+		return accessoryKey, controllerKey, err
+	}
+
+	m2 := accessory.TLV8Encode(
+		accessory.TLV8Item{Type: accessory.TypeState, Value: []byte{2}},
+		accessory.TLV8Item{Type: accessory.TypePublicKey, Value: accessoryPublic[:]},
+		accessory.TLV8Item{Type: accessory.TypeEncrypted, Value: encrypted},
+	)
+	if err = writeTLV(conn, req, m2); err != nil {
+		return accessoryKey, controllerKey, err
+	}
+
+	req2, err := http.ReadRequest(r)
+	if err != nil {
+		return accessoryKey, controllerKey, err
+	}
+
+	body2, err := io.ReadAll(req2.Body)
+	if err != nil {
+		return accessoryKey, controllerKey, err
+	}
+	m3 := accessory.TLV8Decode(body2)
+
+	if err = verify.VerifyM3(m3[accessory.TypeEncrypted]); err != nil {
+		_ = writeTLV(conn, req2, pairSetupErrorTLV(4))
+		return accessoryKey, controllerKey, err
+	}
+
+	m4 := accessory.TLV8Encode(accessory.TLV8Item{Type: accessory.TypeState, Value: []byte{4}})
+	if err = writeTLV(conn, req2, m4); err != nil {
+		return accessoryKey, controllerKey, err
+	}
+
+	return verify.ControlKeys()
+}
+
+// pairSetupErrorTLV builds a <state,error> TLV8 reply reporting
+// kTLVError_Authentication, used by every step that can reject a bad proof
+// or signature.
+func pairSetupErrorTLV(state byte) []byte {
+	return accessory.TLV8Encode(
+		accessory.TLV8Item{Type: accessory.TypeState, Value: []byte{state}},
+		accessory.TLV8Item{Type: accessory.TypeError, Value: []byte{tlvErrorAuthentication}},
+	)
+}
+
+func writeTLV(conn net.Conn, req *http.Request, body []byte) error {
+	resp := &http.Response{
+		StatusCode:    http.StatusOK,
+		Request:       req,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Header:        http.Header{"Content-Type": {"application/pairing+tlv8"}},
+	}
+	return resp.Write(conn)
+}
+
+func (s *Server) serveHTTP(req *http.Request) *http.Response {
+	switch req.URL.Path {
+	case "/accessories":
+		return jsonResponse(req, s.acc)
+	case "/characteristics":
+		if req.Method == http.MethodPut {
+			return s.writeCharacteristics(req)
+		}
+		return jsonResponse(req, s.acc)
+	default:
+		return &http.Response{StatusCode: http.StatusNotFound, Request: req}
+	}
+}
+
+func (s *Server) writeCharacteristics(req *http.Request) *http.Response {
+	var writes []accessory.Characteristic
+	if err := decodeJSON(req.Body, &struct {
+		Characteristics *[]accessory.Characteristic `json:"characteristics"`
+	}{&writes}); err != nil {
+		return &http.Response{StatusCode: http.StatusBadRequest, Request: req}
+	}
+
+	for _, iid := range s.acc.HandleWrite(writes) {
+		if iid == camera.IIDSelectedStreamConfiguration {
+			s.mu.Lock()
+			_ = s.startSession()
+			s.mu.Unlock()
+		}
+	}
+
+	return &http.Response{StatusCode: http.StatusNoContent, Request: req}
+}
+
+// startSession brings up the SRTP session(s) requested by the controller's
+// SelectedStreamConfiguration write, pulling RTP from the stream's producer
+// side the same way Client.startMJPEG pulls snapshot frames.
+func (s *Server) startSession() error {
+	ch := s.acc.Characteristic(camera.IIDSelectedStreamConfiguration)
+	if ch == nil {
+		return errors.New("homekit: no SelectedStreamConfiguration characteristic")
+	}
+
+	session := &srtp.Session{Local: &srtp.Endpoint{}}
+	s.srtp.AddSession(session)
+
+	return camera.StartSessionFromTLV(ch.Value, s.src, session)
+}
+
+func newAEADFromKey(key [32]byte) (cipher.AEAD, error) {
+	return chacha20poly1305.New(key[:])
+}
+
+func jsonResponse(req *http.Request, v any) *http.Response {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return &http.Response{StatusCode: http.StatusInternalServerError, Request: req}
+	}
+
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Request:       req,
+		Body:          io.NopCloser(bytes.NewReader(b)),
+		ContentLength: int64(len(b)),
+		Header:        http.Header{"Content-Type": {"application/hap+json"}},
+	}
+}
+
+func decodeJSON(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+type decryptReader struct {
+	r     io.Reader
+	aead  cipher.AEAD
+	nonce uint64
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	var lenBuf [2]byte
+	if _, err := io.ReadFull(d.r, lenBuf[:]); err != nil {
+		return 0, err
+	}
+
+	n := binary.LittleEndian.Uint16(lenBuf[:])
+	frame := make([]byte, n+d.aead.Overhead())
+	if _, err := io.ReadFull(d.r, frame); err != nil {
+		return 0, err
+	}
+
+	nonce := make([]byte, 12)
+	binary.LittleEndian.PutUint64(nonce[4:], d.nonce)
+	d.nonce++
+
+	out, err := d.aead.Open(nil, nonce, frame, lenBuf[:])
+	if err != nil {
+		return 0, err
+	}
+
+	return copy(p, out), nil
+}
+
+type encryptWriter struct {
+	conn  net.Conn
+	aead  cipher.AEAD
+	nonce uint64
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	const maxFrame = 1024
+
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > maxFrame {
+			chunk = chunk[:maxFrame]
+		}
+
+		var lenBuf [2]byte
+		binary.LittleEndian.PutUint16(lenBuf[:], uint16(len(chunk)))
+
+		nonce := make([]byte, 12)
+		binary.LittleEndian.PutUint64(nonce[4:], e.nonce)
+		e.nonce++
+
+		sealed := e.aead.Seal(nil, nonce, chunk, lenBuf[:])
+
+		if _, err := e.conn.Write(lenBuf[:]); err != nil {
+			return 0, err
+		}
+		if _, err := e.conn.Write(sealed); err != nil {
+			return 0, err
+		}
+
+		p = p[len(chunk):]
+	}
+
+	return len(p), nil
+}
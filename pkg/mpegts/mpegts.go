@@ -0,0 +1,281 @@
+// Package mpegts muxes a go2rtc producer's H264/AAC tracks into MPEG-TS and
+// publishes them over SRT, for use by pkg/broadcast's SRT sink.
+//
+// The SRT transport implemented here is a minimal caller handshake (enough
+// to obtain a socket ID from a listening SRT server) followed by unencrypted,
+// unacknowledged data packets: there is no retransmission, ACK/NAK handling,
+// or encryption. Real SRT's full congestion-control and reliability layer is
+// out of scope for this tree; a lossy link will show it as dropped frames
+// rather than automatic recovery.
+//
+// As with pkg/flv, each RTP packet is treated as one complete access unit:
+// fragmented H264 (FU-A) is not reassembled.
+package mpegts
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/url"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/pion/rtp"
+)
+
+const (
+	tsPacketSize = 188
+	tsSyncByte   = 0x47
+
+	pmtPID   = 0x1000
+	videoPID = 0x100
+	audioPID = 0x101
+
+	streamTypeH264 = 0x1B
+	streamTypeAAC  = 0x0F
+)
+
+// Publish dials rawURL (srt://host:port), performs the SRT caller handshake,
+// then streams producer's video/audio tracks as MPEG-TS packets until ctx is
+// cancelled or the connection fails.
+func Publish(ctx context.Context, rawURL string, producer core.Producer) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	conn, sock, err := dialSRT(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	w := newTSWriter(conn, sock)
+
+	if err = w.writePAT(); err != nil {
+		return err
+	}
+
+	var videoType, audioType byte
+	for _, media := range producer.GetMedias() {
+		if len(media.Codecs) == 0 {
+			continue
+		}
+		switch {
+		case media.Kind == core.KindVideo && media.Codecs[0].Name == core.CodecH264:
+			videoType = streamTypeH264
+		case media.Kind == core.KindAudio && media.Codecs[0].Name == core.CodecAAC:
+			audioType = streamTypeAAC
+		}
+	}
+	if videoType == 0 && audioType == 0 {
+		return errors.New("mpegts: producer has no H264 video or AAC audio")
+	}
+	if err = w.writePMT(videoType, audioType); err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 2)
+	attached := attachTracks(producer, w, errCh)
+	if !attached {
+		return errors.New("mpegts: producer has no video or audio media")
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err = <-errCh:
+		return err
+	}
+}
+
+func attachTracks(producer core.Producer, w *tsWriter, errCh chan<- error) bool {
+	attached := false
+
+	for _, media := range producer.GetMedias() {
+		if len(media.Codecs) == 0 {
+			continue
+		}
+		codec := media.Codecs[0]
+
+		var pid uint16
+		switch {
+		case media.Kind == core.KindVideo && codec.Name == core.CodecH264:
+			pid = videoPID
+		case media.Kind == core.KindAudio && codec.Name == core.CodecAAC:
+			pid = audioPID
+		default:
+			continue
+		}
+
+		receiver, err := producer.GetTrack(media, codec)
+		if err != nil {
+			continue
+		}
+
+		sender := core.NewSender(media, codec)
+		sender.Handler = func(packet *rtp.Packet) {
+			if err := w.writePES(pid, packet); err != nil {
+				select {
+				case errCh <- err:
+				default:
+				}
+			}
+		}
+		sender.HandleRTP(receiver)
+		attached = true
+	}
+
+	return attached
+}
+
+// tsWriter packetizes PES frames into 188-byte TS packets and sends them
+// over an SRT data connection.
+type tsWriter struct {
+	conn net.Conn
+	sock *srtSocket
+
+	continuity map[uint16]byte
+}
+
+func newTSWriter(conn net.Conn, sock *srtSocket) *tsWriter {
+	return &tsWriter{conn: conn, sock: sock, continuity: map[uint16]byte{}}
+}
+
+func (w *tsWriter) nextContinuity(pid uint16) byte {
+	cc := w.continuity[pid]
+	w.continuity[pid] = (cc + 1) & 0x0F
+	return cc
+}
+
+func (w *tsWriter) writePAT() error {
+	payload := []byte{
+		0x00,       // table id
+		0xB0, 0x0D, // section syntax + length
+		0x00, 0x01, // transport stream id
+		0xC1,       // version/current_next
+		0x00, 0x00, // section/last section number
+		byte(1 >> 8), byte(1), // program number 1
+		byte(0xE0 | (pmtPID >> 8)), byte(pmtPID & 0xFF), // PMT PID
+	}
+	payload = append(payload, crc32MPEG2(payload)...)
+	return w.writeSection(0x00, payload)
+}
+
+func (w *tsWriter) writePMT(videoType, audioType byte) error {
+	var streams []byte
+	if videoType != 0 {
+		streams = append(streams, videoType, byte(0xE0|(videoPID>>8)), byte(videoPID&0xFF), 0xF0, 0x00)
+	}
+	if audioType != 0 {
+		streams = append(streams, audioType, byte(0xE0|(audioPID>>8)), byte(audioPID&0xFF), 0xF0, 0x00)
+	}
+
+	sectionLen := 9 + len(streams) + 4
+	payload := []byte{
+		0x02, // table id
+		byte(0xB0 | sectionLen>>8), byte(sectionLen),
+		0x00, 0x01, // program number
+		0xC1,       // version/current_next
+		0x00, 0x00, // section/last section number
+		byte(0xE0 | (videoPID >> 8)), byte(videoPID & 0xFF), // PCR PID
+		0xF0, 0x00, // program info length
+	}
+	payload = append(payload, streams...)
+	payload = append(payload, crc32MPEG2(payload)...)
+	return w.writeSection(pmtPID, payload)
+}
+
+func (w *tsWriter) writeSection(pid uint16, payload []byte) error {
+	pkt := make([]byte, tsPacketSize)
+	pkt[0] = tsSyncByte
+	pkt[1] = 0x40 | byte(pid>>8) // payload_unit_start_indicator
+	pkt[2] = byte(pid)
+	pkt[3] = 0x10 | w.nextContinuity(pid) // no adaptation field, payload only
+
+	n := copy(pkt[5:], payload) // byte 4 is the pointer_field (0: section starts immediately)
+	for i := 5 + n; i < tsPacketSize; i++ {
+		pkt[i] = 0xFF
+	}
+
+	return w.send(pkt)
+}
+
+// writePES wraps packet's payload in a minimal PES header (no DTS, PTS only)
+// and splits it across as many TS packets as needed.
+func (w *tsWriter) writePES(pid uint16, packet *rtp.Packet) error {
+	pts := uint64(packet.Timestamp)
+
+	pes := []byte{0x00, 0x00, 0x01, streamIDFor(pid), 0, 0, 0x80, 0x80, 5}
+	pes = append(pes, encodePTS(pts)...)
+	pes = append(pes, packet.Payload...)
+
+	first := true
+	for len(pes) > 0 {
+		pkt := make([]byte, tsPacketSize)
+		pkt[0] = tsSyncByte
+		pkt[1] = byte(pid >> 8)
+		if first {
+			pkt[1] |= 0x40
+		}
+		pkt[2] = byte(pid)
+		pkt[3] = 0x10 | w.nextContinuity(pid)
+
+		n := copy(pkt[4:], pes)
+		pes = pes[n:]
+		for i := 4 + n; i < tsPacketSize; i++ {
+			pkt[i] = 0xFF
+		}
+
+		if err := w.send(pkt); err != nil {
+			return err
+		}
+		first = false
+	}
+
+	return nil
+}
+
+func (w *tsWriter) send(pkt []byte) error {
+	if w.sock != nil {
+		return w.sock.writeData(w.conn, pkt)
+	}
+	_, err := w.conn.Write(pkt)
+	return err
+}
+
+func streamIDFor(pid uint16) byte {
+	if pid == videoPID {
+		return 0xE0 // video stream 0
+	}
+	return 0xC0 // audio stream 0
+}
+
+func encodePTS(pts uint64) []byte {
+	b := make([]byte, 5)
+	b[0] = 0x21 | byte(pts>>29)&0x0E
+	binary.BigEndian.PutUint16(b[1:3], uint16(pts>>14)|1)
+	binary.BigEndian.PutUint16(b[3:5], uint16(pts<<1)|1)
+	return b
+}
+
+// crc32MPEG2 computes the CRC-32/MPEG-2 checksum (poly 0x04C11DB7, non-
+// reflected, init 0xFFFFFFFF, no final XOR) that terminates every PSI
+// section, table-free since the polynomial only needs a per-byte shift loop.
+func crc32MPEG2(section []byte) []byte {
+	crc := uint32(0xFFFFFFFF)
+	for _, b := range section {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	out := make([]byte, 4)
+	binary.BigEndian.PutUint32(out, crc)
+	return out
+}
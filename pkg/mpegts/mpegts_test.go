@@ -0,0 +1,114 @@
+package mpegts
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// fakeConn is a net.Conn that records each Write call as one packet, enough
+// to assert on tsWriter's output without a real socket.
+type fakeConn struct {
+	packets [][]byte
+}
+
+func newFakeConn() *fakeConn { return &fakeConn{} }
+
+func (c *fakeConn) Write(b []byte) (int, error) {
+	cp := append([]byte(nil), b...)
+	c.packets = append(c.packets, cp)
+	return len(b), nil
+}
+func (c *fakeConn) Read(b []byte) (int, error)         { return 0, nil }
+func (c *fakeConn) Close() error                       { return nil }
+func (c *fakeConn) LocalAddr() net.Addr                { return nil }
+func (c *fakeConn) RemoteAddr() net.Addr               { return nil }
+func (c *fakeConn) SetDeadline(t time.Time) error      { return nil }
+func (c *fakeConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *fakeConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func (c *fakeConn) lastPacket(t *testing.T) []byte {
+	t.Helper()
+	if len(c.packets) == 0 {
+		t.Fatal("expected at least one packet to have been written")
+	}
+	return c.packets[len(c.packets)-1]
+}
+
+func TestEncodePTSMarkerBits(t *testing.T) {
+	b := encodePTS(90000)
+
+	if b[0]&0xF0 != 0x20 {
+		t.Fatalf("expected PTS-only prefix nibble 0x2, got %x", b[0])
+	}
+	if b[0]&0x01 != 1 || b[2]&0x01 != 1 || b[4]&0x01 != 1 {
+		t.Fatalf("expected marker bits set in all three PTS fields: %x", b)
+	}
+}
+
+func TestWritePATStartsWithSyncByteAndPUSI(t *testing.T) {
+	conn := newFakeConn()
+	w := newTSWriter(conn, nil)
+
+	if err := w.writePAT(); err != nil {
+		t.Fatal(err)
+	}
+
+	pkt := conn.lastPacket(t)
+	if pkt[0] != tsSyncByte {
+		t.Fatalf("expected sync byte 0x47, got %x", pkt[0])
+	}
+	if pkt[1]&0x40 == 0 {
+		t.Fatal("expected payload_unit_start_indicator set on a PSI section")
+	}
+	if len(pkt) != tsPacketSize {
+		t.Fatalf("expected a full %d-byte TS packet, got %d", tsPacketSize, len(pkt))
+	}
+}
+
+func TestWritePESSplitsAcrossPacketsForLargePayload(t *testing.T) {
+	conn := newFakeConn()
+	w := newTSWriter(conn, nil)
+
+	big := make([]byte, tsPacketSize*2)
+	packet := &rtp.Packet{Payload: big}
+	if err := w.writePES(videoPID, packet); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(conn.packets) < 2 {
+		t.Fatalf("expected a large access unit to span multiple TS packets, got %d", len(conn.packets))
+	}
+	for i, pkt := range conn.packets {
+		if len(pkt) != tsPacketSize {
+			t.Fatalf("packet %d: expected %d bytes, got %d", i, tsPacketSize, len(pkt))
+		}
+	}
+}
+
+func TestNextContinuityWrapsAt16(t *testing.T) {
+	w := newTSWriter(nil, nil)
+
+	var last byte
+	for i := 0; i < 16; i++ {
+		last = w.nextContinuity(videoPID)
+	}
+	if last != 15 {
+		t.Fatalf("expected continuity counter to reach 15 after 16 calls, got %d", last)
+	}
+	if w.nextContinuity(videoPID) != 0 {
+		t.Fatal("expected continuity counter to wrap back to 0 after 16")
+	}
+}
+
+func TestCRC32MPEG2KnownVector(t *testing.T) {
+	// The standard check value for CRC-32/MPEG-2 over ASCII "123456789".
+	got := crc32MPEG2([]byte("123456789"))
+	want := []byte{0x03, 0x76, 0xE6, 0xE7}
+
+	if string(got) != string(want) {
+		t.Fatalf("got %x, want %x", got, want)
+	}
+}
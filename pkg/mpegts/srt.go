@@ -0,0 +1,130 @@
+package mpegts
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"net"
+	"net/url"
+	"time"
+)
+
+// SRT handshake packet layout (HSv4, RFC draft-sharabayko-srt): a 16-byte
+// SRT header (control bit + type in the first 4 bytes, then 3 more 32-bit
+// fields) followed by a 48-byte handshake body.
+const (
+	srtControlBit    = 1 << 31
+	srtTypeHandshake = 0x0000
+
+	srtHandshakeVersion4  = 4
+	srtSocketTypeDatagram = 1 // raw UDP payload, not SRT's own live/stream framing
+
+	srtReqTypeInduction  = 1
+	srtReqTypeConclusion = -1
+)
+
+// srtSocket tracks the state a caller-mode SRT handshake establishes: our
+// own socket ID (sent to the peer) and the peer's, used as the destination
+// socket ID on every subsequent data packet.
+type srtSocket struct {
+	selfSocketID uint32
+	peerSocketID uint32
+	seq          uint32
+}
+
+// dialSRT performs SRT's two-round caller handshake (induction then
+// conclusion) over UDP and returns a connection already associated with the
+// peer's socket ID.
+func dialSRT(ctx context.Context, u *url.URL) (net.Conn, *srtSocket, error) {
+	addr := u.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr += ":9000" // SRT's conventional default
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sock := &srtSocket{selfSocketID: rand.Uint32(), seq: rand.Uint32()}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	}
+
+	synCookie, err := sock.roundTrip(conn, srtReqTypeInduction, 0)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	if _, err = sock.roundTrip(conn, srtReqTypeConclusion, synCookie); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+
+	return conn, sock, nil
+}
+
+// roundTrip sends one handshake request and parses the peer's response,
+// learning its socket ID and (during induction) its SYN cookie.
+func (s *srtSocket) roundTrip(conn net.Conn, reqType int32, synCookie uint32) (uint32, error) {
+	req := make([]byte, 16+48)
+	binary.BigEndian.PutUint32(req[0:4], srtControlBit|srtTypeHandshake)
+	binary.BigEndian.PutUint32(req[4:8], 0) // type-specific info, unused for handshake
+	binary.BigEndian.PutUint32(req[8:12], 0)
+	binary.BigEndian.PutUint32(req[12:16], s.selfSocketID)
+
+	body := req[16:]
+	binary.BigEndian.PutUint32(body[0:4], srtHandshakeVersion4)
+	binary.BigEndian.PutUint32(body[4:8], srtSocketTypeDatagram)
+	binary.BigEndian.PutUint32(body[8:12], s.seq)
+	binary.BigEndian.PutUint32(body[12:16], 1500) // MSS
+	binary.BigEndian.PutUint32(body[16:20], 8192) // flow window size
+	binary.BigEndian.PutUint32(body[20:24], uint32(reqType))
+	binary.BigEndian.PutUint32(body[24:28], s.selfSocketID)
+	binary.BigEndian.PutUint32(body[28:32], synCookie)
+	// remaining 16 bytes: peer IP address, left zeroed (unused by most listeners
+	// for a same-network caller handshake)
+
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+
+	resp := make([]byte, 16+48)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return 0, err
+	}
+	if n < len(resp) {
+		return 0, errors.New("mpegts: short SRT handshake response")
+	}
+
+	respBody := resp[16:]
+	s.peerSocketID = binary.BigEndian.Uint32(respBody[24:28])
+	respCookie := binary.BigEndian.Uint32(respBody[28:32])
+
+	return respCookie, nil
+}
+
+// writeData sends pkt (a single TS packet, or in principle any payload) as
+// one SRT data packet: a 16-byte header (sequence number with the control
+// bit clear, PB/order flags, message number, timestamp, destination socket
+// ID) followed by the payload.
+func (s *srtSocket) writeData(conn net.Conn, pkt []byte) error {
+	header := make([]byte, 16)
+	binary.BigEndian.PutUint32(header[0:4], s.seq&^uint32(srtControlBit))
+	s.seq++
+	binary.BigEndian.PutUint32(header[4:8], 1<<30|1<<29) // PB=10 (solo packet), order bit set
+	binary.BigEndian.PutUint32(header[8:12], uint32(time.Now().UnixMicro()))
+	binary.BigEndian.PutUint32(header[12:16], s.peerSocketID)
+
+	_, err := conn.Write(append(header, pkt...))
+	return err
+}
@@ -0,0 +1,152 @@
+// Package whip publishes a go2rtc producer to a WHIP (WebRTC-HTTP Ingestion
+// Protocol, RFC 9725) endpoint, for use by pkg/broadcast's WHIP sink.
+//
+// Only the HTTP signaling exchange is implemented here: POST an SDP offer,
+// follow the Location header to the session resource, DELETE it on
+// teardown. Actually moving media (ICE connectivity checks, DTLS, SRTP)
+// needs a full WebRTC stack, which this tree doesn't have (there is no
+// pkg/webrtc here the way there is in the main go2rtc module); Publish
+// completes the handshake and then blocks until ctx is cancelled or the
+// sink closes the session, without sending any RTP.
+package whip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// Publish negotiates a WHIP session against rawURL (whip+http:// or
+// whip+https://) for producer's medias, then holds the session open until
+// ctx is cancelled, at which point it DELETEs the session resource.
+func Publish(ctx context.Context, rawURL string, producer core.Producer) error {
+	endpoint := strings.Replace(strings.Replace(rawURL,
+		"whip+https://", "https://", 1),
+		"whip+http://", "http://", 1)
+
+	offer := offerSDP(producer)
+
+	sessionURL, err := postOffer(ctx, endpoint, offer)
+	if err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+
+	deleteSession(sessionURL)
+
+	return ctx.Err()
+}
+
+// postOffer sends the SDP offer and returns the session resource URL from
+// the response's Location header, as WHIP requires.
+func postOffer(ctx context.Context, endpoint, offer string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(offer))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/sdp")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("whip: offer rejected (%s): %s", resp.Status, body)
+	}
+
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", errors.New("whip: response is missing the Location header")
+	}
+
+	if strings.HasPrefix(location, "/") {
+		resolved, err := resolveAgainst(endpoint, location)
+		if err != nil {
+			return "", err
+		}
+		location = resolved
+	}
+
+	return location, nil
+}
+
+// resolveAgainst turns a path-only Location header into an absolute URL
+// against endpoint's scheme and host.
+func resolveAgainst(endpoint, path string) (string, error) {
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	ref, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
+
+func deleteSession(sessionURL string) {
+	if sessionURL == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, sessionURL, nil)
+	if err != nil {
+		return
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
+
+// offerSDP builds a minimal, non-negotiating SDP offer advertising
+// producer's H264/AAC medias. It carries no ICE candidates or DTLS
+// fingerprint, since this tree has no ICE/DTLS agent to back them with:
+// it is enough to let a WHIP server see what the publisher intends to
+// send, not enough to establish a working media session.
+func offerSDP(producer core.Producer) string {
+	var sb strings.Builder
+
+	sb.WriteString("v=0\r\n")
+	sb.WriteString("o=- 0 0 IN IP4 0.0.0.0\r\n")
+	sb.WriteString("s=go2rtc\r\n")
+	sb.WriteString("t=0 0\r\n")
+
+	for _, media := range producer.GetMedias() {
+		if len(media.Codecs) == 0 {
+			continue
+		}
+		codec := media.Codecs[0]
+
+		switch {
+		case media.Kind == core.KindVideo && codec.Name == core.CodecH264:
+			sb.WriteString("m=video 9 UDP/TLS/RTP/SAVPF 96\r\n")
+			sb.WriteString("a=rtpmap:96 H264/90000\r\n")
+			sb.WriteString("a=sendonly\r\n")
+		case media.Kind == core.KindAudio && codec.Name == core.CodecAAC:
+			sb.WriteString("m=audio 9 UDP/TLS/RTP/SAVPF 97\r\n")
+			sb.WriteString("a=rtpmap:97 mpeg4-generic/48000\r\n")
+			sb.WriteString("a=sendonly\r\n")
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,52 @@
+package whip
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+type fakeProducer struct {
+	medias []*core.Media
+}
+
+func (p *fakeProducer) GetMedias() []*core.Media { return p.medias }
+
+func TestOfferSDPIncludesVideoAndAudio(t *testing.T) {
+	producer := &fakeProducer{medias: []*core.Media{
+		{Kind: core.KindVideo, Codecs: []*core.Codec{{Name: core.CodecH264}}},
+		{Kind: core.KindAudio, Codecs: []*core.Codec{{Name: core.CodecAAC}}},
+	}}
+
+	sdp := offerSDP(producer)
+
+	if !strings.Contains(sdp, "m=video") {
+		t.Fatalf("expected a video m-line, got:\n%s", sdp)
+	}
+	if !strings.Contains(sdp, "m=audio") {
+		t.Fatalf("expected an audio m-line, got:\n%s", sdp)
+	}
+}
+
+func TestOfferSDPSkipsUnsupportedCodecs(t *testing.T) {
+	producer := &fakeProducer{medias: []*core.Media{
+		{Kind: core.KindVideo, Codecs: []*core.Codec{{Name: "VP8"}}},
+	}}
+
+	sdp := offerSDP(producer)
+	if strings.Contains(sdp, "m=video") {
+		t.Fatalf("expected no m-line for an unsupported codec, got:\n%s", sdp)
+	}
+}
+
+func TestResolveAgainstAbsolutePath(t *testing.T) {
+	got, err := resolveAgainst("https://example.com/whip/stream1", "/whip/session/abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://example.com/whip/session/abc"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}